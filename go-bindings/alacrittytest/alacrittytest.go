@@ -0,0 +1,312 @@
+// Package alacrittytest provides golden-file assertions for terminal screens.
+//
+// A golden file records the text of every row and, optionally, the style
+// runs of the screen. Run the tests with -alacrittytest.update to rewrite
+// golden files from the current screen state.
+package alacrittytest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	alacritty "github.com/example/alacritty-go"
+)
+
+// update is prefixed with the package name so that it does not clash with
+// an -update flag of the test binary
+var update = flag.Bool("alacrittytest.update", false, "rewrite golden screen files in testdata")
+
+// Option configures AssertScreen and AssertTerminal
+type Option func(*options)
+
+type options struct {
+	style bool
+}
+
+// WithStyle makes AssertScreen compare colors and attributes as well as text
+func WithStyle() Option {
+	return func(o *options) {
+		o.style = true
+	}
+}
+
+// AssertTerminal compares the current screen of term against
+// testdata/<golden>.golden. With -alacrittytest.update the golden file is
+// rewritten instead.
+func AssertTerminal(t testing.TB, term *alacritty.Terminal, golden string, opts ...Option) {
+	t.Helper()
+
+	s, err := term.Snapshot()
+	if err != nil {
+		t.Fatalf("alacrittytest: failed to capture screen: %v", err)
+	}
+	AssertScreen(t, s, golden, opts...)
+}
+
+// AssertScreen compares a snapshot against testdata/<golden>.golden. With
+// -alacrittytest.update the golden file is rewritten instead.
+func AssertScreen(t testing.TB, s *alacritty.Screen, golden string, opts ...Option) {
+	t.Helper()
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	got := capture(s)
+
+	path := filepath.Join("testdata", golden+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("alacrittytest: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got.format(o.style)), 0o644); err != nil {
+			t.Fatalf("alacrittytest: %v", err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("alacrittytest: %v (run with -alacrittytest.update to create it)", err)
+	}
+	want, err := parseGolden(string(data))
+	if err != nil {
+		t.Fatalf("alacrittytest: %s: %v", path, err)
+	}
+
+	if o.style && !want.hasStyle {
+		t.Fatalf("alacrittytest: %s has no style section (run with -alacrittytest.update to rewrite it)", path)
+	}
+
+	if diff := diffScreens(want, got, o.style); diff != "" {
+		t.Errorf("screen does not match %s (run with -alacrittytest.update to rewrite it):\n%s", path, diff)
+	}
+}
+
+// screen is the comparable form of a terminal screen
+type screen struct {
+	cols, rows int
+	text       []string
+	styles     [][]string // per row, per column style description
+	hasStyle   bool
+}
+
+func capture(snapshot *alacritty.Screen) *screen {
+	cols, rows := snapshot.Size()
+
+	s := &screen{
+		cols:     int(cols),
+		rows:     int(rows),
+		text:     make([]string, rows),
+		styles:   make([][]string, rows),
+		hasStyle: true,
+	}
+	for y := uint32(0); y < rows; y++ {
+		line := snapshot.Line(y)
+
+		var b strings.Builder
+		s.styles[y] = make([]string, cols)
+		for x, cell := range line {
+			if cell.Char == 0 {
+				b.WriteRune(' ')
+			} else {
+				b.WriteRune(cell.Char)
+			}
+			s.styles[y][x] = styleOf(cell)
+		}
+		s.text[y] = strings.TrimRight(b.String(), " ")
+	}
+
+	return s
+}
+
+// styleOf describes the attributes of a cell that differ from the default
+// style, or returns "" for a default cell. Colors are compared by palette
+// index, so the default colors are left out whatever they resolve to.
+func styleOf(cell alacritty.Cell) string {
+	var attrs []string
+	if cell.FgIndex != alacritty.IndexForeground {
+		attrs = append(attrs, "fg="+hexColor(cell.FgColor))
+	}
+	if cell.BgIndex != alacritty.IndexBackground {
+		attrs = append(attrs, "bg="+hexColor(cell.BgColor))
+	}
+	if cell.Bold {
+		attrs = append(attrs, "bold")
+	}
+	if cell.Dim {
+		attrs = append(attrs, "dim")
+	}
+	if cell.Italic {
+		attrs = append(attrs, "italic")
+	}
+	if cell.Underline {
+		attrs = append(attrs, "underline")
+	}
+	if cell.Inverse {
+		attrs = append(attrs, "inverse")
+	}
+	if cell.Hidden {
+		attrs = append(attrs, "hidden")
+	}
+	if cell.Strikeout {
+		attrs = append(attrs, "strikeout")
+	}
+	if cell.Hyperlink.URI != "" {
+		attrs = append(attrs, "link="+cell.Hyperlink.URI)
+	}
+	return strings.Join(attrs, " ")
+}
+
+func hexColor(c alacritty.RGB) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// format renders the screen in golden file syntax:
+//
+//	size 80x24
+//	--- text
+//	<one line per row, trailing blanks trimmed>
+//	--- style
+//	row 0 col 7-14: fg=#ff0000 bold
+func (s *screen) format(withStyle bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "size %dx%d\n", s.cols, s.rows)
+	b.WriteString("--- text\n")
+	for _, line := range s.text {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	if !withStyle {
+		return b.String()
+	}
+
+	b.WriteString("--- style\n")
+	for y, row := range s.styles {
+		for x := 0; x < len(row); {
+			end := x
+			for end+1 < len(row) && row[end+1] == row[x] {
+				end++
+			}
+			if row[x] != "" {
+				fmt.Fprintf(&b, "row %d col %d-%d: %s\n", y, x, end, row[x])
+			}
+			x = end + 1
+		}
+	}
+	return b.String()
+}
+
+func parseGolden(data string) (*screen, error) {
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("golden file is truncated")
+	}
+
+	s := &screen{}
+	if _, err := fmt.Sscanf(lines[0], "size %dx%d", &s.cols, &s.rows); err != nil {
+		return nil, fmt.Errorf("bad size header %q", lines[0])
+	}
+	if lines[1] != "--- text" {
+		return nil, fmt.Errorf("missing text section")
+	}
+	if len(lines) < 2+s.rows {
+		return nil, fmt.Errorf("text section has %d rows, want %d", len(lines)-2, s.rows)
+	}
+	s.text = lines[2 : 2+s.rows]
+
+	rest := lines[2+s.rows:]
+	if len(rest) == 0 {
+		return s, nil
+	}
+	if rest[0] != "--- style" {
+		return nil, fmt.Errorf("unexpected line %q after text section", rest[0])
+	}
+
+	s.hasStyle = true
+	s.styles = make([][]string, s.rows)
+	for y := range s.styles {
+		s.styles[y] = make([]string, s.cols)
+	}
+	for _, line := range rest[1:] {
+		var y, from, to int
+		head, attrs, _ := strings.Cut(line, ": ")
+		if _, err := fmt.Sscanf(head, "row %d col %d-%d", &y, &from, &to); err != nil {
+			return nil, fmt.Errorf("bad style line %q", line)
+		}
+		if y < 0 || y >= s.rows || from < 0 || to >= s.cols || from > to {
+			return nil, fmt.Errorf("style line %q is outside the %dx%d screen", line, s.cols, s.rows)
+		}
+		for x := from; x <= to; x++ {
+			s.styles[y][x] = attrs
+		}
+	}
+
+	return s, nil
+}
+
+// diffScreens returns a readable description of every difference between
+// want and got, or "" if they match
+func diffScreens(want, got *screen, withStyle bool) string {
+	var b strings.Builder
+	if want.cols != got.cols || want.rows != got.rows {
+		fmt.Fprintf(&b, "size: want %dx%d, got %dx%d\n", want.cols, want.rows, got.cols, got.rows)
+		return b.String()
+	}
+
+	for y := 0; y < want.rows; y++ {
+		w, g := []rune(want.text[y]), []rune(got.text[y])
+		if string(w) == string(g) {
+			continue
+		}
+
+		marks := make([]rune, max(len(w), len(g)))
+		for x := range marks {
+			if x < len(w) && x < len(g) && w[x] == g[x] {
+				marks[x] = ' '
+			} else {
+				marks[x] = '^'
+			}
+		}
+		fmt.Fprintf(&b, "row %d:\n  want |%s|\n  got  |%s|\n        %s\n",
+			y, string(w), string(g), strings.TrimRight(string(marks), " "))
+	}
+
+	if !withStyle {
+		return b.String()
+	}
+
+	for y := 0; y < want.rows; y++ {
+		for x := 0; x < want.cols; {
+			if want.styles[y][x] == got.styles[y][x] {
+				x++
+				continue
+			}
+
+			end := x
+			for end+1 < want.cols &&
+				want.styles[y][end+1] == want.styles[y][x] &&
+				got.styles[y][end+1] == got.styles[y][x] {
+				end++
+			}
+			fmt.Fprintf(&b, "row %d col %d-%d: want %s, got %s\n",
+				y, x, end, describe(want.styles[y][x]), describe(got.styles[y][x]))
+			x = end + 1
+		}
+	}
+
+	return b.String()
+}
+
+func describe(style string) string {
+	if style == "" {
+		return "default style"
+	}
+	return style
+}
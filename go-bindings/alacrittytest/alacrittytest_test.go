@@ -0,0 +1,173 @@
+package alacrittytest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	alacritty "github.com/example/alacritty-go"
+)
+
+func TestAssertScreenText(t *testing.T) {
+	term := alacritty.NewTerminal(20, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	if _, err := term.Write([]byte("Hello, World!\r\nLine 2")); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	AssertTerminal(t, term, "plain")
+}
+
+func TestAssertScreenStyle(t *testing.T) {
+	term := alacritty.NewTerminal(20, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	if _, err := term.Write([]byte("Normal \x1b[1;31mBold Red\x1b[0m done")); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	snapshot, err := term.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	AssertScreen(t, snapshot, "styled", WithStyle())
+}
+
+func TestStyleOf(t *testing.T) {
+	plain := alacritty.Cell{
+		Char:    'x',
+		FgIndex: alacritty.IndexForeground,
+		BgIndex: alacritty.IndexBackground,
+	}
+
+	custom := plain
+	custom.FgColor = alacritty.RGB{R: 0x10, G: 0x20, B: 0x30}
+	custom.BgColor = alacritty.RGB{R: 0xf0, G: 0xf0, B: 0xf0}
+
+	red := plain
+	red.FgIndex, red.FgColor = 1, alacritty.RGB{R: 0xff}
+
+	attrs := plain
+	attrs.Dim, attrs.Hidden, attrs.Strikeout = true, true, true
+	attrs.Hyperlink = alacritty.Hyperlink{ID: "1", URI: "https://example.com"}
+
+	tests := []struct {
+		name     string
+		cell     alacritty.Cell
+		expected string
+	}{
+		{"Default colors", plain, ""},
+		{"Custom default colors", custom, ""},
+		{"Indexed color", red, "fg=#ff0000"},
+		{"Attributes", attrs, "dim hidden strikeout link=https://example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := styleOf(tt.cell); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// recorder is a testing.TB that records the failure of an assertion
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestAssertScreenStyleMissing(t *testing.T) {
+	term := alacritty.NewTerminal(20, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	if _, err := term.Write([]byte("Hello, World!\r\nLine 2")); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	// The golden file of plain text has no style section to compare with
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		AssertTerminal(r, term, "plain", WithStyle())
+	}()
+	<-done
+
+	if !strings.Contains(r.failure, "no style section") {
+		t.Errorf("Expected a missing style section failure, got %q", r.failure)
+	}
+}
+
+func TestGoldenRoundTrip(t *testing.T) {
+	s := &screen{
+		cols:     6,
+		rows:     2,
+		text:     []string{"ab cd", ""},
+		styles:   [][]string{{"bold", "bold", "", "fg=#ff0000", "fg=#ff0000", ""}, make([]string, 6)},
+		hasStyle: true,
+	}
+
+	formatted := s.format(true)
+	want := "size 6x2\n--- text\nab cd\n\n--- style\nrow 0 col 0-1: bold\nrow 0 col 3-4: fg=#ff0000\n"
+	if formatted != want {
+		t.Fatalf("format: expected %q, got %q", want, formatted)
+	}
+
+	parsed, err := parseGolden(formatted)
+	if err != nil {
+		t.Fatalf("Failed to parse golden data: %v", err)
+	}
+	if diff := diffScreens(parsed, s, true); diff != "" {
+		t.Errorf("Round trip produced a diff:\n%s", diff)
+	}
+}
+
+func TestDiffMarksChangedCells(t *testing.T) {
+	want := &screen{
+		cols:   5,
+		rows:   1,
+		text:   []string{"hello"},
+		styles: [][]string{{"", "", "", "", ""}},
+	}
+	got := &screen{
+		cols:   5,
+		rows:   1,
+		text:   []string{"hallo"},
+		styles: [][]string{{"", "bold", "bold", "", ""}},
+	}
+
+	diff := diffScreens(want, got, true)
+	for _, expected := range []string{
+		"  want |hello|\n",
+		"  got  |hallo|\n",
+		"         ^\n",
+		"row 0 col 1-2: want default style, got bold\n",
+	} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("Diff is missing %q:\n%s", expected, diff)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package alacritty
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ANSIOptions controls the ANSI re-serialization
+type ANSIOptions struct {
+	// Scrollback replays the scrollback history ahead of the screen so that
+	// it ends up in the history of the receiving terminal
+	Scrollback bool
+}
+
+// ANSI returns a byte stream that reproduces the current screen, cursor
+// position, pen and modes when written to a fresh terminal of the same size
+func (t *Terminal) ANSI(opts ANSIOptions) ([]byte, error) {
+	s, err := t.snapshot(opts.Scrollback)
+	if err != nil {
+		return nil, err
+	}
+	return s.ANSI(opts), nil
+}
+
+// ANSI returns a byte stream that reproduces the screen, cursor position,
+// pen and modes when written to a fresh terminal of the same size. The
+// scrollback is only included if the snapshot has one.
+func (s *Screen) ANSI(opts ANSIOptions) []byte {
+	return encodeANSI(s, opts.Scrollback)
+}
+
+// defaultModes are the modes of a freshly created terminal
+const defaultModes = ModeShowCursor | ModeLineWrap | ModeAlternateScroll | ModeUrgencyHints
+
+// privateModes maps modes to their DEC private mode numbers
+var privateModes = []struct {
+	mode   Mode
+	number int
+}{
+	{ModeAppCursor, 1},
+	{ModeOrigin, 6},
+	{ModeLineWrap, 7},
+	{ModeShowCursor, 25},
+	{ModeMouseReportClick, 1000},
+	{ModeMouseDrag, 1002},
+	{ModeMouseMotion, 1003},
+	{ModeFocusInOut, 1004},
+	{ModeUTF8Mouse, 1005},
+	{ModeSGRMouse, 1006},
+	{ModeAlternateScroll, 1007},
+	{ModeUrgencyHints, 1042},
+	{ModeBracketedPaste, 2004},
+}
+
+// encodeANSI serializes the screens, scrolling region, cursor, pen and
+// modes of s, preceded by its history if scrollback is set. The primary
+// screen behind the alternate screen is written first, so that it is shown
+// again when the application leaves the alternate screen.
+func encodeANSI(s *Screen, scrollback bool) []byte {
+	var e ansiEncoder
+	e.buf.WriteString("\x1b[?7h\x1b[H\x1b[2J")
+
+	lines := s.allLines(scrollback)
+	scrolling := scrollback && len(s.history) > 0
+	if p := s.primary; p != nil {
+		e.writeLines(lines[:len(lines)-len(s.lines)], p.lines, scrolling)
+		e.writeCursor(p.lines, p.cursorX, p.cursorY, 0, p.wrapPending, p.pen)
+		e.buf.WriteString("\x1b[?1049h\x1b[H")
+		lines, scrolling = s.lines, false
+	}
+	e.writeLines(nil, lines, scrolling)
+
+	// Setting the region moves the cursor home, which is placed below
+	if s.scrollBottom != 0 {
+		fmt.Fprintf(&e.buf, "\x1b[%d;%dr", s.scrollTop+1, s.scrollBottom)
+	}
+
+	modes := s.modes &^ ModeAltScreen
+	for _, m := range privateModes {
+		if modes&m.mode == defaultModes&m.mode {
+			continue
+		}
+		if modes&m.mode != 0 {
+			fmt.Fprintf(&e.buf, "\x1b[?%dh", m.number)
+		} else {
+			fmt.Fprintf(&e.buf, "\x1b[?%dl", m.number)
+		}
+	}
+	if modes&ModeInsert != 0 {
+		e.buf.WriteString("\x1b[4h")
+	}
+	if modes&ModeLineFeedNewLine != 0 {
+		e.buf.WriteString("\x1b[20h")
+	}
+	if modes&ModeAppKeypad != 0 {
+		e.buf.WriteString("\x1b=")
+	}
+	if modes&ModeDisambiguateEscCodes != 0 {
+		e.buf.WriteString("\x1b[>1u")
+	}
+
+	// The origin mode makes cursor positions relative to the region
+	var origin uint32
+	if modes&ModeOrigin != 0 {
+		origin = s.scrollTop
+	}
+	e.writeCursor(s.lines, s.cursorX, s.cursorY, origin, s.wrapPending, s.pen)
+	return e.buf.Bytes()
+}
+
+// ansiEncoder writes the sequences of encodeANSI, keeping track of the
+// rendition and hyperlink in effect
+type ansiEncoder struct {
+	buf  bytes.Buffer
+	sgr  sgrState
+	link Hyperlink
+}
+
+// writeLines writes history and lines from the cursor on. When scrolling is
+// set every line is written so that the lines above the last screenful
+// scroll into the history, otherwise trailing blank lines are left out.
+func (e *ansiEncoder) writeLines(history, lines [][]Cell, scrolling bool) {
+	if len(history) > 0 {
+		lines = append(append([][]Cell(nil), history...), lines...)
+	}
+
+	last := len(lines)
+	if !scrolling {
+		for last > 0 && lineEnd(lines[last-1]) == 0 {
+			last--
+		}
+	}
+
+	for i, line := range lines[:last] {
+		end := lineEnd(line)
+		// A wrapped line is printed up to the last column so that the
+		// receiving terminal wraps it into the next line as well
+		wrapped := len(line) > 0 && line[len(line)-1].Wrapline &&
+			i+1 < last && lineEnd(lines[i+1]) > 0
+		if wrapped {
+			end = len(line)
+		}
+
+		for _, cell := range line[:end] {
+			if !cell.WideCharSpacer {
+				e.writeCell(cell)
+			}
+		}
+
+		if i+1 < last && !wrapped {
+			// New lines created by scrolling take the current background
+			if e.sgr != (sgrState{}) {
+				e.buf.WriteString("\x1b[0m")
+				e.sgr = sgrState{}
+			}
+			e.buf.WriteString("\r\n")
+		}
+	}
+}
+
+// writeCell writes a cell in its rendition and hyperlink
+func (e *ansiEncoder) writeCell(cell Cell) {
+	next := sgrStateOf(cell)
+	e.buf.WriteString(e.sgr.transition(next))
+	e.sgr = next
+	if cell.Hyperlink != e.link {
+		writeOSC8(&e.buf, cell.Hyperlink)
+		e.link = cell.Hyperlink
+	}
+
+	if cell.Char == 0 {
+		e.buf.WriteByte(' ')
+	} else {
+		e.buf.WriteRune(cell.Char)
+	}
+}
+
+// writeCursor ends the hyperlink and places the cursor of lines, where
+// line origin is the first line cursor positions count from, then selects
+// the pen. A cursor waiting to wrap past the last column is placed by
+// writing the last cell of its line again, which leaves it waiting as well.
+func (e *ansiEncoder) writeCursor(lines [][]Cell, x, y, origin uint32, wrapPending bool, pen Cell) {
+	row := y - min(y, origin)
+	wrapPending = wrapPending && y < uint32(len(lines)) && len(lines[y]) > 0
+	if wrapPending {
+		line := lines[y]
+		last := len(line) - 1
+		if line[last].WideCharSpacer && last > 0 {
+			last--
+		}
+		fmt.Fprintf(&e.buf, "\x1b[%d;%dH", row+1, last+1)
+		e.writeCell(line[last])
+	}
+	if e.link != (Hyperlink{}) {
+		writeOSC8(&e.buf, Hyperlink{})
+		e.link = Hyperlink{}
+	}
+	if !wrapPending {
+		fmt.Fprintf(&e.buf, "\x1b[%d;%dH", row+1, x+1)
+	}
+	next := sgrStateOf(pen)
+	e.buf.WriteString(e.sgr.transition(next))
+	e.sgr = next
+}
+
+// writeOSC8 starts a hyperlink, or ends it for the zero Hyperlink
+func writeOSC8(buf *bytes.Buffer, link Hyperlink) {
+	if link.ID != "" {
+		fmt.Fprintf(buf, "\x1b]8;id=%s;%s\x1b\\", link.ID, link.URI)
+	} else {
+		fmt.Fprintf(buf, "\x1b]8;;%s\x1b\\", link.URI)
+	}
+}
+
+// lineEnd returns the length of line without its trailing blank cells in
+// the default style and without a hyperlink
+func lineEnd(line []Cell) int {
+	end := len(line)
+	for end > 0 {
+		cell := line[end-1]
+		if (cell.Char != ' ' && cell.Char != 0) || sgrStateOf(cell) != (sgrState{}) || cell.Hyperlink != (Hyperlink{}) {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// sgrColor is a color as it is selected by SGR: a palette index and, for
+// direct colors and palette entries without an SGR code, the RGB value.
+// The zero value is the default color.
+type sgrColor struct {
+	set   bool
+	index uint16
+	rgb   RGB
+}
+
+// sgrState is the graphic rendition of a cell
+type sgrState struct {
+	fg, bg                                                   sgrColor
+	bold, dim, italic, underline, inverse, hidden, strikeout bool
+}
+
+func sgrStateOf(cell Cell) sgrState {
+	return sgrState{
+		fg:        sgrColorOf(cell.FgIndex, cell.FgColor, IndexForeground),
+		bg:        sgrColorOf(cell.BgIndex, cell.BgColor, IndexBackground),
+		bold:      cell.Bold,
+		dim:       cell.Dim,
+		italic:    cell.Italic,
+		underline: cell.Underline,
+		inverse:   cell.Inverse,
+		hidden:    cell.Hidden,
+		strikeout: cell.Strikeout,
+	}
+}
+
+func sgrColorOf(index uint16, rgb RGB, defaultIndex uint16) sgrColor {
+	switch {
+	case index == defaultIndex:
+		return sgrColor{}
+	case index < 256:
+		return sgrColor{set: true, index: index}
+	default:
+		return sgrColor{set: true, index: IndexDirect, rgb: rgb}
+	}
+}
+
+// params returns the SGR parameters selecting c, with base 30 for the
+// foreground and 40 for the background
+func (c sgrColor) params(base int) string {
+	switch {
+	case !c.set:
+		return strconv.Itoa(base + 9)
+	case c.index < 8:
+		return strconv.Itoa(base + int(c.index))
+	case c.index < 16:
+		return strconv.Itoa(base + 60 + int(c.index) - 8)
+	case c.index < 256:
+		return fmt.Sprintf("%d;5;%d", base+8, c.index)
+	default:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, c.rgb.R, c.rgb.G, c.rgb.B)
+	}
+}
+
+// transition returns the SGR sequence changing the rendition from s to
+// next, or "" if they are equal
+func (s sgrState) transition(next sgrState) string {
+	if s == next {
+		return ""
+	}
+
+	var params []string
+	attr := func(from, to bool, on, off string) {
+		switch {
+		case to && !from:
+			params = append(params, on)
+		case from && !to:
+			params = append(params, off)
+		}
+	}
+
+	// Bold and dim share their reset code
+	if (s.bold && !next.bold) || (s.dim && !next.dim) {
+		params = append(params, "22")
+		s.bold, s.dim = false, false
+	}
+	attr(s.bold, next.bold, "1", "22")
+	attr(s.dim, next.dim, "2", "22")
+	attr(s.italic, next.italic, "3", "23")
+	attr(s.underline, next.underline, "4", "24")
+	attr(s.inverse, next.inverse, "7", "27")
+	attr(s.hidden, next.hidden, "8", "28")
+	attr(s.strikeout, next.strikeout, "9", "29")
+	if s.fg != next.fg {
+		params = append(params, next.fg.params(30))
+	}
+	if s.bg != next.bg {
+		params = append(params, next.bg.params(40))
+	}
+
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
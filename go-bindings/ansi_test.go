@@ -0,0 +1,318 @@
+package alacritty
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestANSIRoundTrip(t *testing.T) {
+	term := NewTerminal(20, 5)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	input := "plain \x1b[1;31mbold red\x1b[0m\r\n" +
+		"\x1b[44m blue bg \x1b[0m \x1b[38;5;202mx\x1b[38;2;1;2;3my\x1b[0m\r\n" +
+		"a line that is long enough to wrap\r\n" +
+		"\x1b[?2004h\x1b[?25l\x1b[3;7H\x1b[1;35m"
+	if _, err := term.Write([]byte(input)); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	data, err := term.ANSI(ANSIOptions{})
+	if err != nil {
+		t.Fatalf("Failed to serialize terminal: %v", err)
+	}
+
+	restored := NewTerminal(20, 5)
+	if restored == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer restored.Close()
+
+	if _, err := restored.Write(data); err != nil {
+		t.Fatalf("Failed to write serialized terminal: %v", err)
+	}
+
+	for y := uint32(0); y < 5; y++ {
+		want, err := term.GetLine(y)
+		if err != nil {
+			t.Fatalf("Failed to get line %d: %v", y, err)
+		}
+		got, err := restored.GetLine(y)
+		if err != nil {
+			t.Fatalf("Failed to get line %d: %v", y, err)
+		}
+		for x := range want {
+			if want[x] != got[x] {
+				t.Errorf("Cell (%d, %d): expected %+v, got %+v", x, y, want[x], got[x])
+			}
+		}
+	}
+
+	wantX, wantY, _ := term.GetCursor()
+	gotX, gotY, _ := restored.GetCursor()
+	if wantX != gotX || wantY != gotY {
+		t.Errorf("Cursor: expected (%d, %d), got (%d, %d)", wantX, wantY, gotX, gotY)
+	}
+
+	wantPen, _ := term.Pen()
+	gotPen, _ := restored.Pen()
+	if wantPen != gotPen {
+		t.Errorf("Pen: expected %+v, got %+v", wantPen, gotPen)
+	}
+
+	wantModes, _ := term.Modes()
+	gotModes, _ := restored.Modes()
+	if wantModes != gotModes {
+		t.Errorf("Modes: expected %#x, got %#x", wantModes, gotModes)
+	}
+}
+
+// roundTripANSI writes input to a terminal, serializes it and writes the
+// result to a fresh terminal of the same size
+func roundTripANSI(t *testing.T, cols, rows uint32, input string) (term, restored *Terminal) {
+	t.Helper()
+
+	term = NewTerminal(cols, rows)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	t.Cleanup(term.Close)
+	if _, err := term.Write([]byte(input)); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	data, err := term.ANSI(ANSIOptions{})
+	if err != nil {
+		t.Fatalf("Failed to serialize terminal: %v", err)
+	}
+
+	restored = NewTerminal(cols, rows)
+	if restored == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	t.Cleanup(restored.Close)
+	if _, err := restored.Write(data); err != nil {
+		t.Fatalf("Failed to write serialized terminal: %v", err)
+	}
+	return term, restored
+}
+
+// writeBoth writes the same input to both terminals
+func writeBoth(t *testing.T, input string, terms ...*Terminal) {
+	t.Helper()
+	for _, term := range terms {
+		if _, err := term.Write([]byte(input)); err != nil {
+			t.Fatalf("Failed to write %q: %v", input, err)
+		}
+	}
+}
+
+// compareScreens fails unless both terminals show the same screen, cursor,
+// scrolling region and primary screen
+func compareScreens(t *testing.T, term, restored *Terminal) {
+	t.Helper()
+
+	want, err := term.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	got, err := restored.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+
+	if got.String() != want.String() {
+		t.Errorf("Screen: expected\n%s\ngot\n%s", want, got)
+	}
+	if got.cursorX != want.cursorX || got.cursorY != want.cursorY || got.wrapPending != want.wrapPending {
+		t.Errorf("Cursor: expected (%d, %d, wrap %v), got (%d, %d, wrap %v)",
+			want.cursorX, want.cursorY, want.wrapPending, got.cursorX, got.cursorY, got.wrapPending)
+	}
+	if got.scrollTop != want.scrollTop || got.scrollBottom != want.scrollBottom {
+		t.Errorf("Scrolling region: expected %d-%d, got %d-%d",
+			want.scrollTop, want.scrollBottom, got.scrollTop, got.scrollBottom)
+	}
+	if got.modes != want.modes {
+		t.Errorf("Modes: expected %#x, got %#x", want.modes, got.modes)
+	}
+	if (got.primary == nil) != (want.primary == nil) {
+		t.Fatalf("Primary screen: expected %v, got %v", want.primary != nil, got.primary != nil)
+	}
+	if want.primary != nil {
+		wantPrimary := &Screen{rows: want.rows, lines: want.primary.lines}
+		gotPrimary := &Screen{rows: got.rows, lines: got.primary.lines}
+		if gotPrimary.String() != wantPrimary.String() {
+			t.Errorf("Primary screen: expected\n%s\ngot\n%s", wantPrimary, gotPrimary)
+		}
+		if got.primary.cursorX != want.primary.cursorX || got.primary.cursorY != want.primary.cursorY {
+			t.Errorf("Primary cursor: expected (%d, %d), got (%d, %d)",
+				want.primary.cursorX, want.primary.cursorY, got.primary.cursorX, got.primary.cursorY)
+		}
+	}
+}
+
+func TestANSIRoundTripAltScreen(t *testing.T) {
+	term, restored := roundTripANSI(t, 20, 4,
+		"$ less file\r\n\x1b[?1049h\x1b[Hfile contents\r\n\x1b[7m(END)\x1b[0m")
+	compareScreens(t, term, restored)
+
+	// Leaving the alternate screen shows the primary screen again
+	writeBoth(t, "\x1b[?1049l$ ", term, restored)
+	compareScreens(t, term, restored)
+	if text := restored.String(); !strings.HasPrefix(text, "$ less file") {
+		t.Errorf("Expected the primary screen after leaving the alternate screen, got\n%s", text)
+	}
+}
+
+func TestANSIRoundTripScrollRegion(t *testing.T) {
+	term, restored := roundTripANSI(t, 10, 5, "top\r\n\x1b[2;4r\x1b[3;1Hmiddle")
+	compareScreens(t, term, restored)
+
+	// Line feeds at the bottom of the region scroll only the region
+	writeBoth(t, "\x1b[4;1H\n\nlast", term, restored)
+	compareScreens(t, term, restored)
+}
+
+func TestANSIRoundTripWrapPending(t *testing.T) {
+	term, restored := roundTripANSI(t, 5, 3, "ab\x1b[1;4H\x1b[1mxy")
+	compareScreens(t, term, restored)
+
+	// The next character wraps to the next line
+	writeBoth(t, "z", term, restored)
+	compareScreens(t, term, restored)
+}
+
+func TestSGRTransition(t *testing.T) {
+	red := sgrState{fg: sgrColor{set: true, index: 1}}
+	boldRed := red
+	boldRed.bold = true
+	dimRed := red
+	dimRed.dim = true
+	cube := sgrState{fg: sgrColor{set: true, index: 202}, bg: sgrColor{set: true, index: 12}}
+	direct := sgrState{bg: sgrColor{set: true, index: IndexDirect, rgb: RGB{R: 1, G: 2, B: 3}}}
+
+	tests := []struct {
+		name     string
+		from, to sgrState
+		expected string
+	}{
+		{"Unchanged", red, red, ""},
+		{"Add bold", red, boldRed, "\x1b[1m"},
+		{"Bold to dim", boldRed, dimRed, "\x1b[22;2m"},
+		{"Back to default", boldRed, sgrState{}, "\x1b[22;39m"},
+		{"Indexed colors", sgrState{}, cube, "\x1b[38;5;202;104m"},
+		{"Direct color", cube, direct, "\x1b[39;48;2;1;2;3m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.from.transition(tt.to); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEncodeANSI(t *testing.T) {
+	bold := plainCell('b')
+	bold.Bold = true
+	wrapped := plainCell('c')
+	wrapped.Wrapline = true
+
+	lines := [][]Cell{
+		{plainCell('a'), bold, plainCell(' ')},
+		{plainCell('x'), plainCell('y'), wrapped},
+		{plainCell('z'), plainCell(' '), plainCell(' ')},
+		{plainCell(' '), plainCell(' '), plainCell(' ')},
+	}
+
+	pen := plainCell(' ')
+	pen.Italic = true
+
+	s := &Screen{
+		cols: 3, rows: 4, lines: lines,
+		cursorX: 1, cursorY: 2,
+		pen:   pen,
+		modes: defaultModes | ModeBracketedPaste,
+	}
+	got := string(encodeANSI(s, false))
+	expected := "\x1b[?7h\x1b[H\x1b[2J" +
+		"a\x1b[1mb\x1b[0m\r\n" +
+		"xyc" +
+		"z" +
+		"\x1b[?2004h" +
+		"\x1b[3;2H" +
+		"\x1b[3m"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestEncodeANSIScreenState(t *testing.T) {
+	line := func(text string) []Cell {
+		var cells []Cell
+		for _, c := range text {
+			cells = append(cells, plainCell(c))
+		}
+		return cells
+	}
+	blank := line("   ")
+
+	tests := []struct {
+		name     string
+		screen   *Screen
+		expected string
+	}{
+		{
+			"Alternate screen",
+			&Screen{
+				cols: 3, rows: 2, lines: [][]Cell{line("b  "), blank},
+				cursorX: 1, pen: plainCell(' '), modes: defaultModes | ModeAltScreen,
+				primary: &primaryScreen{
+					lines:   [][]Cell{line("a  "), line("$  ")},
+					cursorX: 2, cursorY: 1, pen: plainCell(' '),
+				},
+			},
+			"\x1b[?7h\x1b[H\x1b[2Ja\r\n$\x1b[2;3H" +
+				"\x1b[?1049h\x1b[Hb\x1b[1;2H",
+		},
+		{
+			"Scrolling region",
+			&Screen{
+				cols: 3, rows: 3, lines: [][]Cell{blank, blank, blank},
+				cursorY: 1, scrollTop: 1, scrollBottom: 2,
+				pen: plainCell(' '), modes: defaultModes,
+			},
+			"\x1b[?7h\x1b[H\x1b[2J\x1b[2;2r\x1b[2;1H",
+		},
+		{
+			"Origin mode",
+			&Screen{
+				cols: 3, rows: 3, lines: [][]Cell{blank, blank, blank},
+				cursorY: 1, scrollTop: 1, scrollBottom: 2,
+				pen: plainCell(' '), modes: defaultModes | ModeOrigin,
+			},
+			"\x1b[?7h\x1b[H\x1b[2J\x1b[2;2r\x1b[?6h\x1b[1;1H",
+		},
+		{
+			"Pending wrap",
+			&Screen{
+				cols: 3, rows: 1, lines: [][]Cell{line("abc")},
+				cursorX: 2, wrapPending: true,
+				pen: plainCell(' '), modes: defaultModes,
+			},
+			"\x1b[?7h\x1b[H\x1b[2Jabc\x1b[1;3Hc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(encodeANSI(tt.screen, false)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
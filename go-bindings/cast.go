@@ -0,0 +1,297 @@
+package alacritty
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// CastHeader is the first line of an asciicast v2 recording
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     uint32            `json:"width"`
+	Height    uint32            `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Asciicast event types
+const (
+	CastOutput = "o"
+	CastInput  = "i"
+	CastResize = "r"
+	CastMarker = "m"
+)
+
+// CastEvent is an event of an asciicast v2 recording
+type CastEvent struct {
+	Time time.Duration
+	Type string
+	Data string
+}
+
+// Recorder writes everything passed through it to a Terminal as an
+// asciicast v2 recording
+type Recorder struct {
+	mu      sync.Mutex
+	term    *Terminal
+	w       io.Writer
+	start   time.Time
+	now     func() time.Time
+	partial []byte // incomplete UTF-8 sequence held back from the last write
+}
+
+// NewRecorder writes the asciicast header for term to w and returns a
+// Recorder whose Write and Resize also record events to w
+func NewRecorder(w io.Writer, term *Terminal, title string) (*Recorder, error) {
+	return newRecorder(w, term, title, time.Now)
+}
+
+func newRecorder(w io.Writer, term *Terminal, title string, now func() time.Time) (*Recorder, error) {
+	cols, rows, err := term.GetSize()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{term: term, w: w, start: now(), now: now}
+	header, err := json.Marshal(CastHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", header); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Write passes data to the terminal and records it as an output event
+func (r *Recorder) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := r.term.Write(data)
+	if err != nil {
+		return n, err
+	}
+
+	// Events hold strings, so a UTF-8 sequence split across writes is
+	// recorded with the write that completes it
+	pending := append(r.partial, data...)
+	cut := len(pending)
+	for i := len(pending) - 1; i >= 0 && i >= len(pending)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(pending[i]) {
+			if !utf8.FullRune(pending[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.partial = append([]byte(nil), pending[cut:]...)
+
+	if cut == 0 {
+		return len(data), nil
+	}
+	if err := r.event(CastOutput, string(pending[:cut])); err != nil {
+		return len(data), err
+	}
+	return len(data), nil
+}
+
+// Resize resizes the terminal and records a resize event
+func (r *Recorder) Resize(cols, rows uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.term.Resize(cols, rows); err != nil {
+		return err
+	}
+	return r.event(CastResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+func (r *Recorder) event(kind, data string) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	elapsed := r.now().Sub(r.start).Seconds()
+	_, err = fmt.Fprintf(r.w, "[%s, %q, %s]\n", strconv.FormatFloat(elapsed, 'f', 6, 64), kind, encoded)
+	return err
+}
+
+// CastReader decodes an asciicast v2 recording
+type CastReader struct {
+	Header  CastHeader
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewCastReader reads the header of the recording in r
+func NewCastReader(r io.Reader) (*CastReader, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	c := &CastReader{scanner: scanner}
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("asciicast: missing header")
+	}
+	c.line++
+
+	if err := json.Unmarshal(scanner.Bytes(), &c.Header); err != nil {
+		return nil, fmt.Errorf("asciicast: invalid header: %w", err)
+	}
+	if c.Header.Version != 2 {
+		return nil, fmt.Errorf("asciicast: unsupported version %d", c.Header.Version)
+	}
+
+	return c, nil
+}
+
+// Next returns the next event, or io.EOF at the end of the recording
+func (c *CastReader) Next() (CastEvent, error) {
+	for c.scanner.Scan() {
+		c.line++
+		line := strings.TrimSpace(c.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var fields [3]json.RawMessage
+		var event CastEvent
+		var seconds float64
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return CastEvent{}, fmt.Errorf("asciicast: line %d: %w", c.line, err)
+		}
+		if err := json.Unmarshal(fields[0], &seconds); err != nil {
+			return CastEvent{}, fmt.Errorf("asciicast: line %d: invalid time: %w", c.line, err)
+		}
+		if err := json.Unmarshal(fields[1], &event.Type); err != nil {
+			return CastEvent{}, fmt.Errorf("asciicast: line %d: invalid event type: %w", c.line, err)
+		}
+		if err := json.Unmarshal(fields[2], &event.Data); err != nil {
+			return CastEvent{}, fmt.Errorf("asciicast: line %d: invalid event data: %w", c.line, err)
+		}
+		event.Time = time.Duration(seconds * float64(time.Second))
+		return event, nil
+	}
+
+	if err := c.scanner.Err(); err != nil {
+		return CastEvent{}, err
+	}
+	return CastEvent{}, io.EOF
+}
+
+// PlayOptions controls PlayCast
+type PlayOptions struct {
+	// RealTime waits between events as long as the recording did,
+	// otherwise events are played as fast as possible
+	RealTime bool
+	// Speed divides the delays in real time playback, 1 if zero
+	Speed float64
+	// MaxIdle caps the delays in real time playback, unlimited if zero
+	MaxIdle time.Duration
+}
+
+// PlayCast feeds the output and resize events of a recording into term
+func PlayCast(ctx context.Context, term *Terminal, r io.Reader, opts PlayOptions) error {
+	cast, err := NewCastReader(r)
+	if err != nil {
+		return err
+	}
+	return cast.play(ctx, term, opts)
+}
+
+// LoadCast plays a recording as fast as possible into a new terminal of
+// the recorded size and returns the terminal
+func LoadCast(r io.Reader) (*Terminal, error) {
+	cast, err := NewCastReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	term, err := New(cast.Header.Width, cast.Header.Height)
+	if err != nil {
+		return nil, err
+	}
+	if err := cast.play(context.Background(), term, PlayOptions{}); err != nil {
+		term.Close()
+		return nil, err
+	}
+	return term, nil
+}
+
+func (c *CastReader) play(ctx context.Context, term *Terminal, opts PlayOptions) error {
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	var last time.Duration
+	for {
+		event, err := c.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if opts.RealTime {
+			delay := time.Duration(float64(event.Time-last) / speed)
+			if opts.MaxIdle > 0 && delay > opts.MaxIdle {
+				delay = opts.MaxIdle
+			}
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		last = event.Time
+
+		if err := event.apply(term); err != nil {
+			return err
+		}
+	}
+}
+
+// apply passes an output or resize event to term and ignores other events
+func (e CastEvent) apply(term *Terminal) error {
+	switch e.Type {
+	case CastOutput:
+		if _, err := term.Write([]byte(e.Data)); err != nil {
+			return err
+		}
+	case CastResize:
+		var cols, rows uint32
+		if _, err := fmt.Sscanf(e.Data, "%dx%d", &cols, &rows); err != nil {
+			return fmt.Errorf("asciicast: invalid resize %q", e.Data)
+		}
+		return term.Resize(cols, rows)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package alacritty
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	term := NewTerminal(20, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	clock := time.Unix(1700000000, 0)
+	now := func() time.Time { return clock }
+
+	var buf bytes.Buffer
+	rec, err := newRecorder(&buf, term, "demo", now)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	clock = clock.Add(250 * time.Millisecond)
+	rec.Write([]byte("héllo\r\n"))
+	clock = clock.Add(time.Second)
+	// The 3-byte '€' is split across two writes
+	rec.Write([]byte("\xe2\x82"))
+	rec.Write([]byte("\xac\"\x1b[0m"))
+	clock = clock.Add(time.Second)
+	rec.Resize(30, 3)
+
+	expected := `{"version":2,"width":20,"height":3,"timestamp":1700000000,"title":"demo"}
+[0.250000, "o", "héllo\r\n"]
+[1.250000, "o", "€\"\u001b[0m"]
+[2.250000, "r", "30x3"]
+`
+	if buf.String() != expected {
+		t.Errorf("Recording:\nexpected %s\ngot      %s", expected, buf.String())
+	}
+}
+
+func TestCastReader(t *testing.T) {
+	cast := `{"version": 2, "width": 80, "height": 24}
+[0.5, "o", "hi"]
+
+[1.25, "i", "x"]
+[2, "r", "100x30"]
+`
+	r, err := NewCastReader(strings.NewReader(cast))
+	if err != nil {
+		t.Fatalf("Failed to read header: %v", err)
+	}
+	if r.Header.Width != 80 || r.Header.Height != 24 {
+		t.Errorf("Header: expected 80x24, got %dx%d", r.Header.Width, r.Header.Height)
+	}
+
+	expected := []CastEvent{
+		{Time: 500 * time.Millisecond, Type: CastOutput, Data: "hi"},
+		{Time: 1250 * time.Millisecond, Type: CastInput, Data: "x"},
+		{Time: 2 * time.Second, Type: CastResize, Data: "100x30"},
+	}
+	for i, want := range expected {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("Event %d: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last event, got %v", err)
+	}
+}
+
+func TestCastReaderRejectsVersion1(t *testing.T) {
+	if _, err := NewCastReader(strings.NewReader(`{"version": 1, "width": 80, "height": 24}`)); err == nil {
+		t.Error("Expected an error for an asciicast v1 header")
+	}
+}
+
+func TestLoadCast(t *testing.T) {
+	cast := `{"version": 2, "width": 10, "height": 2}
+[0.1, "o", "$ ls\r\n"]
+[0.2, "i", "ignored"]
+[0.3, "o", "a.txt"]
+`
+	term, err := LoadCast(strings.NewReader(cast))
+	if err != nil {
+		t.Fatalf("Failed to load cast: %v", err)
+	}
+	defer term.Close()
+
+	expected := "$ ls      \na.txt     "
+	if got := term.String(); got != expected {
+		t.Errorf("Screen: expected %q, got %q", expected, got)
+	}
+}
+
+func TestPlayCastRealTimeCancel(t *testing.T) {
+	term := NewTerminal(10, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	cast := `{"version": 2, "width": 10, "height": 2}
+[0.0, "o", "a"]
+[60.0, "o", "b"]
+`
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := PlayCast(ctx, term, strings.NewReader(cast), PlayOptions{RealTime: true})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	cell, _ := term.GetCell(0, 0)
+	if cell.Char != 'a' {
+		t.Errorf("Expected 'a' to be played before the delay, got '%c'", cell.Char)
+	}
+}
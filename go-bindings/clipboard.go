@@ -0,0 +1,137 @@
+package alacritty
+
+/*
+#include "alacritty_ffi.h"
+*/
+import "C"
+import (
+	"encoding/base64"
+	"runtime"
+	"unsafe"
+)
+
+// Selection is a clipboard named by an OSC 52 request
+type Selection byte
+
+// Selections of OSC 52
+const (
+	SelectionClipboard Selection = 'c'
+	SelectionPrimary   Selection = 'p'
+	// SelectionSelect is the selection the terminal configures, routed to
+	// the clipboard by default
+	SelectionSelect Selection = 's'
+)
+
+// Clipboard handles the requests applications such as tmux and neovim send
+// with OSC 52. Its methods are called by Write, without holding the
+// terminal lock.
+type Clipboard interface {
+	// Store copies text to a selection
+	Store(sel Selection, text string) error
+	// Load returns the text of a selection, which is sent to the
+	// application through the writer of WithReplyWriter
+	Load(sel Selection) (string, error)
+}
+
+// DefaultClipboardLimit is the size in bytes of the largest text stored or
+// loaded unless WithClipboardLimit sets another
+const DefaultClipboardLimit = 1 << 20
+
+// defaultClipboardRoutes sends the clipboard and primary selection to
+// themselves and the configurable selection to the clipboard
+var defaultClipboardRoutes = map[Selection]Selection{
+	SelectionClipboard: SelectionClipboard,
+	SelectionPrimary:   SelectionPrimary,
+	SelectionSelect:    SelectionClipboard,
+}
+
+// clipboard is the Clipboard of a terminal with its limits
+type clipboard struct {
+	c      Clipboard
+	routes map[Selection]Selection
+	limit  int
+}
+
+// clipboardRequest is an OSC 52 request copied from the library
+type clipboardRequest struct {
+	sel  Selection
+	load bool
+	// st is set when the request ended with ESC \, which the answer repeats
+	st   bool
+	data string
+}
+
+// clipboardRequests takes the pending OSC 52 requests. The caller holds
+// the lock.
+func (t *Terminal) clipboardRequests() ([]clipboardRequest, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.terminal_get_clipboard_requests(t.ptr, nil, 0)
+	if result <= 0 {
+		if result < 0 {
+			return nil, lastError("Write", result)
+		}
+		return nil, nil
+	}
+
+	cRequests := make([]C.CClipboardRequest, result)
+	result = C.terminal_get_clipboard_requests(t.ptr, &cRequests[0], C.size_t(len(cRequests)))
+	if result < 0 {
+		return nil, lastError("Write", result)
+	}
+	requests := make([]clipboardRequest, len(cRequests))
+	for i, r := range cRequests {
+		requests[i] = clipboardRequest{
+			sel:  Selection(r.selection),
+			load: r.load != 0,
+			st:   r.st != 0,
+			data: C.GoStringN((*C.char)(unsafe.Pointer(r.data)), C.int(r.data_len)),
+		}
+	}
+
+	if result := C.terminal_clear_clipboard_requests(t.ptr); result < 0 {
+		return nil, lastError("Write", result)
+	}
+	return requests, nil
+}
+
+// handleClipboard passes OSC 52 requests to the clipboard and answers the
+// loads. Requests for selections without a route, invalid base64 and text
+// over the limit are ignored. It is called without holding the lock.
+func (t *Terminal) handleClipboard(requests []clipboardRequest) error {
+	for _, r := range requests {
+		sel, ok := t.clipboard.routes[r.sel]
+		if !ok {
+			continue
+		}
+
+		if !r.load {
+			text, err := base64.StdEncoding.DecodeString(r.data)
+			if err != nil || len(text) > t.clipboard.limit {
+				continue
+			}
+			// The application cannot learn of a failure to copy
+			_ = t.clipboard.c.Store(sel, string(text))
+			continue
+		}
+
+		if t.replies == nil {
+			continue
+		}
+		text, err := t.clipboard.c.Load(sel)
+		if err != nil || len(text) > t.clipboard.limit {
+			// An empty answer tells the application the load failed
+			text = ""
+		}
+		terminator := "\x07"
+		if r.st {
+			terminator = "\x1b\\"
+		}
+		answer := "\x1b]52;" + string(r.sel) + ";" + base64.StdEncoding.EncodeToString([]byte(text)) + terminator
+		if _, err := t.replies.Write([]byte(answer)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package alacritty
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeClipboard records stores and loads the text of its selections
+type fakeClipboard struct {
+	selections map[Selection]string
+	stores     []string
+}
+
+func (c *fakeClipboard) Store(sel Selection, text string) error {
+	c.stores = append(c.stores, string(sel)+":"+text)
+	return nil
+}
+
+func (c *fakeClipboard) Load(sel Selection) (string, error) {
+	text, ok := c.selections[sel]
+	if !ok {
+		return "", errors.New("empty selection")
+	}
+	return text, nil
+}
+
+func TestHandleClipboard(t *testing.T) {
+	c := &fakeClipboard{selections: map[Selection]string{SelectionClipboard: "yank", SelectionPrimary: "too long"}}
+	var replies bytes.Buffer
+	term := &Terminal{
+		clipboard: &clipboard{c: c, routes: defaultClipboardRoutes, limit: 5},
+		replies:   &replies,
+	}
+
+	err := term.handleClipboard([]clipboardRequest{
+		{sel: 'c', data: "aGVsbG8="},     // hello
+		{sel: 's', data: "d29ybGQ="},     // world, routed to the clipboard
+		{sel: 'p', data: "dG9vIGxvbmc="}, // over the limit
+		{sel: 'c', data: "not base64!"},
+		{sel: '0', data: "aGVsbG8="}, // no route
+		{sel: 'c', load: true},
+		{sel: 's', load: true, st: true},
+		{sel: 'p', load: true},
+		{sel: '0', load: true},
+	})
+	if err != nil {
+		t.Fatalf("handleClipboard: %v", err)
+	}
+
+	if expected := []string{"c:hello", "c:world"}; !reflect.DeepEqual(c.stores, expected) {
+		t.Errorf("Expected stores %q, got %q", expected, c.stores)
+	}
+	expected := "\x1b]52;c;eWFuaw==\x07\x1b]52;s;eWFuaw==\x1b\\\x1b]52;p;\x07"
+	if replies.String() != expected {
+		t.Errorf("Expected replies %q, got %q", expected, replies.String())
+	}
+}
+
+func TestClipboardPolicy(t *testing.T) {
+	c := &fakeClipboard{selections: map[Selection]string{SelectionClipboard: "secret"}}
+	var replies bytes.Buffer
+	term, err := New(20, 4, WithClipboard(c), WithReplyWriter(&replies))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+
+	// Reads are denied by default
+	if _, err := term.Write([]byte("\x1b]52;c;aGk=\x07\x1b]52;c;?\x07")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if expected := []string{"c:hi"}; !reflect.DeepEqual(c.stores, expected) {
+		t.Errorf("Expected stores %q, got %q", expected, c.stores)
+	}
+	if replies.Len() != 0 {
+		t.Errorf("Expected no answer to a load, got %q", replies.String())
+	}
+
+	term, err = New(20, 4, WithClipboard(c), WithReplyWriter(&replies), WithOSC52(OSC52ReadWrite))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+	if _, err := term.Write([]byte("\x1b]52;c;?\x07")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if expected := "\x1b]52;c;c2VjcmV0\x07"; replies.String() != expected {
+		t.Errorf("Expected answer %q, got %q", expected, replies.String())
+	}
+}
+
+func TestClipboardLimitInLibrary(t *testing.T) {
+	c := &fakeClipboard{}
+	term, err := New(20, 4, WithClipboard(c), WithClipboardLimit(5))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+
+	// The library queues only the stores whose base64 fits the limit
+	_, events, err := term.write([]byte("\x1b]52;c;aGVsbG8=\x07\x1b]52;c;aGVsbG8sIHdvcmxk\x07"))
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if len(events.clipboard) != 1 || events.clipboard[0].data != "aGVsbG8=" {
+		t.Errorf("Expected only the store of hello, got %+v", events.clipboard)
+	}
+}
@@ -0,0 +1,72 @@
+// Package color holds the color types of the terminal, which the alacritty
+// package aliases. It does not use cgo, so that packages working with
+// colors only, such as theme, build and test without the native library.
+package color
+
+// RGB represents an RGB color
+type RGB struct {
+	R, G, B uint8
+}
+
+// Palette is the table of colors used to resolve named and indexed colors
+type Palette struct {
+	Foreground RGB
+	Background RGB
+
+	// ANSI holds the 8 normal colors followed by the 8 bright colors
+	ANSI [16]RGB
+}
+
+// DefaultPalette returns the colors the terminal uses when no theme is set
+func DefaultPalette() Palette {
+	return Palette{
+		Foreground: RGB{255, 255, 255},
+		Background: RGB{0, 0, 0},
+		ANSI: [16]RGB{
+			{0, 0, 0},
+			{255, 0, 0},
+			{0, 255, 0},
+			{255, 255, 0},
+			{0, 0, 255},
+			{255, 0, 255},
+			{0, 255, 255},
+			{255, 255, 255},
+			{128, 128, 128},
+			{255, 128, 128},
+			{128, 255, 128},
+			{255, 255, 128},
+			{128, 128, 255},
+			{255, 128, 255},
+			{128, 255, 255},
+			{255, 255, 255},
+		},
+	}
+}
+
+// DefaultDimFactor is the brightness of dim text relative to its color,
+// the factor alacritty uses
+const DefaultDimFactor = 0.66
+
+// Options controls how alacritty's Cell.EffectiveColors resolves the colors
+// a cell is drawn with. The zero value only applies inverse and hidden
+// text.
+type Options struct {
+	// Palette provides the bright colors of BoldIsBright. Exporters use the
+	// palette of the screen if it is nil, EffectiveColors DefaultPalette.
+	Palette *Palette
+	// BoldIsBright draws bold text in the colors 0-7 with the bright colors
+	// 8-15, as many terminals do
+	BoldIsBright bool
+	// Dim holds the colors of dim text in the ANSI colors, such as the dim
+	// section of a theme. A normal and its bright color share a dim color.
+	Dim *[8]RGB
+	// DimFactor scales the foreground of dim text that Dim does not cover,
+	// DefaultDimFactor in alacritty. Zero leaves it unchanged.
+	DimFactor float64
+	// DimForeground replaces the default foreground of dim text, nil to
+	// scale it by DimFactor
+	DimForeground *RGB
+	// BrightForeground replaces the default foreground of bold text with
+	// BoldIsBright, nil to keep it
+	BrightForeground *RGB
+}
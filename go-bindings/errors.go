@@ -0,0 +1,103 @@
+package alacritty
+
+/*
+#include "alacritty_ffi.h"
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// Errors reported by Terminal and Parser methods, to be matched with
+// errors.Is
+var (
+	// ErrClosed is returned by every method of a closed terminal, parser or
+	// replay
+	ErrClosed = errors.New("already closed")
+	// ErrOutOfBounds is returned for a position outside the screen or the
+	// scrollback history
+	ErrOutOfBounds = errors.New("position out of bounds")
+	// ErrInvalidSize is returned for a size with zero columns or rows
+	ErrInvalidSize = errors.New("invalid terminal size")
+	// ErrInvalidState is returned by UnmarshalBinary for data that was not
+	// encoded by MarshalBinary
+	ErrInvalidState = errors.New("invalid terminal state")
+	// ErrUnknownCheckpoint is returned for a released checkpoint or one of
+	// another terminal
+	ErrUnknownCheckpoint = errors.New("unknown checkpoint")
+	// ErrPoisoned is returned by every method of a terminal or parser after
+	// the library panicked on it, and by New if it panicked creating one.
+	// Only Close is still useful. Panics are caught unless the library is
+	// built with the abort-on-panic release profile.
+	ErrPoisoned = errors.New("terminal is poisoned by a panic")
+)
+
+// Error codes returned by the native library
+const (
+	CodeNullPointer       = C.ERROR_NULL_POINTER
+	CodeOutOfBounds       = C.ERROR_OUT_OF_BOUNDS
+	CodeInvalidSize       = C.ERROR_INVALID_SIZE
+	CodeInvalidState      = C.ERROR_INVALID_STATE
+	CodeUnknownCheckpoint = C.ERROR_UNKNOWN_CHECKPOINT
+	CodePoisoned          = C.ERROR_POISONED
+)
+
+// codeErrors maps error codes to the sentinel errors they match
+var codeErrors = map[int]error{
+	CodeOutOfBounds:       ErrOutOfBounds,
+	CodeInvalidSize:       ErrInvalidSize,
+	CodeInvalidState:      ErrInvalidState,
+	CodeUnknownCheckpoint: ErrUnknownCheckpoint,
+	CodePoisoned:          ErrPoisoned,
+}
+
+// Error is a failure of a Terminal method reported by the native library
+type Error struct {
+	// Op is the method that failed
+	Op string
+	// Code is one of the Code* error codes
+	Code int
+	// Detail describes the failure, or is empty if the library gave none
+	Detail string
+}
+
+func (e *Error) Error() string {
+	detail := e.Detail
+	if detail == "" {
+		if err, ok := codeErrors[e.Code]; ok {
+			detail = err.Error()
+		} else {
+			detail = fmt.Sprintf("error %d", e.Code)
+		}
+	}
+	return "alacritty: " + e.Op + ": " + detail
+}
+
+// Is reports whether target is the sentinel error for the code of e
+func (e *Error) Is(target error) bool {
+	err, ok := codeErrors[e.Code]
+	return ok && err == target
+}
+
+// lastError returns the error for a failed call of op with the detail
+// recorded by the library. The library records the detail for the calling
+// thread, so the caller locks the goroutine to its OS thread from before the
+// failing call until lastError returns.
+func lastError(op string, code C.int) error {
+	err := &Error{Op: op, Code: int(code)}
+	if code == C.ERROR_NULL_POINTER {
+		// The library records no detail for invalid arguments
+		return err
+	}
+
+	n := C.terminal_last_error(nil, 0)
+	if n > 0 {
+		buf := make([]byte, n)
+		C.terminal_last_error((*C.uint8_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
+		err.Detail = string(buf)
+	}
+
+	return err
+}
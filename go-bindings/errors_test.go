@@ -0,0 +1,96 @@
+package alacritty
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestErrorsMatchSentinels(t *testing.T) {
+	term := NewTerminal(10, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+
+	if _, err := term.GetLine(3); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("GetLine(3): expected ErrOutOfBounds, got %v", err)
+	}
+	if _, err := term.GetCell(10, 0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("GetCell(10, 0): expected ErrOutOfBounds, got %v", err)
+	}
+	if _, err := term.GetHistoryLine(0); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("GetHistoryLine(0): expected ErrOutOfBounds, got %v", err)
+	}
+
+	err := term.Resize(0, 5)
+	if !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Resize(0, 5): expected ErrInvalidSize, got %v", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeInvalidSize || e.Op != "Resize" || e.Detail == "" {
+		t.Errorf("Expected a detailed Resize error, got %#v", err)
+	}
+
+	if err := term.Rollback(Checkpoint{id: 42}); !errors.Is(err, ErrUnknownCheckpoint) {
+		t.Errorf("Rollback: expected ErrUnknownCheckpoint, got %v", err)
+	}
+
+	term.Close()
+	if _, err := term.Write([]byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close: expected ErrClosed, got %v", err)
+	}
+	if _, err := term.GetLine(0); !errors.Is(err, ErrClosed) {
+		t.Errorf("GetLine after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestErrorDetailConcurrentReaders(t *testing.T) {
+	term := NewTerminal(10, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	// Readers failing at once each get the detail of their own call
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(y uint32) {
+			defer wg.Done()
+			want := fmt.Sprintf("line %d outside the 3 screen lines", y)
+			for n := 0; n < 200; n++ {
+				_, err := term.GetLine(y)
+				var e *Error
+				if !errors.As(err, &e) || e.Detail != want {
+					t.Errorf("GetLine(%d): expected detail %q, got %v", y, want, err)
+					return
+				}
+			}
+		}(uint32(3 + i))
+	}
+	wg.Wait()
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := &Error{Op: "GetLine", Code: CodeOutOfBounds}
+	if got := err.Error(); got != "alacritty: GetLine: position out of bounds" {
+		t.Errorf("Unexpected message %q", got)
+	}
+	if errors.Is(err, ErrInvalidSize) {
+		t.Error("Expected an out of bounds error not to match ErrInvalidSize")
+	}
+
+	err = &Error{Op: "Write", Code: CodePoisoned, Detail: "panic: index out of bounds"}
+	if !errors.Is(err, ErrPoisoned) {
+		t.Error("Expected a poisoned error to match ErrPoisoned")
+	}
+	if got := err.Error(); got != "alacritty: Write: panic: index out of bounds" {
+		t.Errorf("Unexpected message %q", got)
+	}
+
+	err = &Error{Op: "Write", Code: CodeNullPointer}
+	if got := err.Error(); got != "alacritty: Write: error -1" {
+		t.Errorf("Unexpected message %q", got)
+	}
+}
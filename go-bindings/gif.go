@@ -0,0 +1,137 @@
+package alacritty
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// TimedWrite is a chunk of terminal input recorded at an offset from the
+// start of a session
+type TimedWrite struct {
+	Time time.Duration
+	Data []byte
+}
+
+// GIFOptions controls how a recorded session is animated
+type GIFOptions struct {
+	RenderOptions
+
+	// MaxIdle caps the delay between frames, 2s if zero
+	MaxIdle time.Duration
+	// MinFrameDelay merges writes closer together than this into one frame,
+	// 20ms if zero
+	MinFrameDelay time.Duration
+	// FinalDelay is how long the last frame is shown, MaxIdle if zero
+	FinalDelay time.Duration
+	// LoopCount is passed to gif.GIF: 0 loops forever, -1 plays once
+	LoopCount int
+}
+
+// EncodeGIF replays writes into a fresh cols x rows terminal and encodes
+// the screen after each write as a frame of an animated GIF, starting with
+// the first write. Frame delays follow the recorded timing and frames
+// without a visible change are merged into the previous one.
+func EncodeGIF(w io.Writer, cols, rows uint32, writes []TimedWrite, opts GIFOptions) error {
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 2 * time.Second
+	}
+	if opts.MinFrameDelay <= 0 {
+		opts.MinFrameDelay = 20 * time.Millisecond
+	}
+	if opts.FinalDelay <= 0 {
+		opts.FinalDelay = opts.MaxIdle
+	}
+
+	term, err := New(cols, rows)
+	if err != nil {
+		return err
+	}
+	defer term.Close()
+
+	anim := &gif.GIF{LoopCount: opts.LoopCount}
+	var prev *image.RGBA
+	var frameStart time.Duration
+
+	addFrame := func(at time.Duration) error {
+		img, ok := term.RenderImage(opts.RenderOptions).(*image.RGBA)
+		if !ok {
+			return fmt.Errorf("failed to render terminal")
+		}
+
+		if prev != nil {
+			anim.Delay[len(anim.Delay)-1] = gifDelay(at-frameStart, opts.MaxIdle)
+			if bytes.Equal(img.Pix, prev.Pix) {
+				// Keep showing the previous frame until the next change
+				return nil
+			}
+		}
+
+		anim.Image = append(anim.Image, paletted(img))
+		anim.Delay = append(anim.Delay, 0)
+		prev, frameStart = img, at
+		return nil
+	}
+
+	batchStart := time.Duration(-1)
+	for i, write := range writes {
+		if _, err := term.Write(write.Data); err != nil {
+			return err
+		}
+		if batchStart < 0 {
+			batchStart = write.Time
+		}
+		if i+1 < len(writes) && writes[i+1].Time-batchStart < opts.MinFrameDelay {
+			continue
+		}
+		if err := addFrame(batchStart); err != nil {
+			return err
+		}
+		batchStart = -1
+	}
+	if len(anim.Image) == 0 {
+		if err := addFrame(0); err != nil {
+			return err
+		}
+	}
+	anim.Delay[len(anim.Delay)-1] = gifDelay(opts.FinalDelay, opts.FinalDelay)
+
+	return gif.EncodeAll(w, anim)
+}
+
+// gifDelay converts d, capped at limit, into GIF hundredths of a second
+func gifDelay(d, limit time.Duration) int {
+	if d > limit {
+		d = limit
+	}
+	return int(d / (10 * time.Millisecond))
+}
+
+// paletted converts a rendered frame to a paletted image, using its exact
+// colors when there are at most 256 of them
+func paletted(img *image.RGBA) *image.Paletted {
+	seen := make(map[color.RGBA]bool)
+	var pal color.Palette
+	for i := 0; i < len(img.Pix); i += 4 {
+		c := color.RGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: img.Pix[i+3]}
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		pal = append(pal, c)
+		if len(pal) > 256 {
+			pal = palette.Plan9
+			break
+		}
+	}
+
+	out := image.NewPaletted(img.Bounds(), pal)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	return out
+}
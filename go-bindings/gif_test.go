@@ -0,0 +1,58 @@
+package alacritty
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestEncodeGIF(t *testing.T) {
+	writes := []TimedWrite{
+		{Time: 0, Data: []byte("$ ")},
+		{Time: 500 * time.Millisecond, Data: []byte("ls")},
+		{Time: 510 * time.Millisecond, Data: []byte("\r\n")},
+		{Time: 800 * time.Millisecond, Data: []byte("\x07")},
+		{Time: 10 * time.Second, Data: []byte("file.txt")},
+	}
+
+	var buf bytes.Buffer
+	err := EncodeGIF(&buf, 20, 3, writes, GIFOptions{MaxIdle: time.Second, FinalDelay: 3 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode GIF: %v", err)
+	}
+
+	// "$ ", "$ ls" with the newline merged in, then "file.txt". The bell
+	// changes nothing and extends the "ls" frame up to the idle cap.
+	expected := []int{50, 100, 300}
+	if len(anim.Delay) != len(expected) {
+		t.Fatalf("Expected %d frames, got %d with delays %v", len(expected), len(anim.Delay), anim.Delay)
+	}
+	for i, delay := range expected {
+		if anim.Delay[i] != delay {
+			t.Errorf("Frame %d: expected delay %d, got %d", i, delay, anim.Delay[i])
+		}
+	}
+}
+
+func TestGIFDelay(t *testing.T) {
+	tests := []struct {
+		d, limit time.Duration
+		expected int
+	}{
+		{250 * time.Millisecond, time.Second, 25},
+		{5 * time.Second, 2 * time.Second, 200},
+		{5 * time.Millisecond, time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		if got := gifDelay(tt.d, tt.limit); got != tt.expected {
+			t.Errorf("gifDelay(%v, %v): expected %d, got %d", tt.d, tt.limit, tt.expected, got)
+		}
+	}
+}
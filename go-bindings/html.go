@@ -0,0 +1,207 @@
+package alacritty
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// HTMLMode selects how colors and attributes are written to HTML
+type HTMLMode int
+
+const (
+	// HTMLInline writes colors and attributes as inline style attributes
+	HTMLInline HTMLMode = iota
+	// HTMLClasses writes the 16 palette colors, the default colors and the
+	// attributes as CSS classes, so that a stylesheet can theme the output.
+	// Other colors are still written inline.
+	HTMLClasses
+)
+
+// HTMLOptions controls the HTML export
+type HTMLOptions struct {
+	Mode HTMLMode
+	// ClassPrefix is prepended to every CSS class name, "term" if empty
+	ClassPrefix string
+	// Stylesheet emits a <style> element defining the classes in HTMLClasses mode
+	Stylesheet bool
+	// Scrollback includes the scrollback history above the screen
+	Scrollback bool
+	// Colors controls how bold, dim, inverse and hidden text is colored
+	Colors ColorOptions
+}
+
+// HTML returns the screen as a <pre> element with a span for every run of
+// cells that share colors and attributes. OSC 8 hyperlinks become links,
+// unless their scheme is not http, https, ftp, file or mailto.
+func (t *Terminal) HTML(opts HTMLOptions) (string, error) {
+	s, err := t.snapshot(opts.Scrollback)
+	if err != nil {
+		return "", err
+	}
+	return s.HTML(opts), nil
+}
+
+// HTML is Terminal.HTML for a snapshot. The scrollback is only included if
+// the snapshot has one.
+func (s *Screen) HTML(opts HTMLOptions) string {
+	return renderHTML(s.allLines(opts.Scrollback), s.palette, opts)
+}
+
+func renderHTML(lines [][]Cell, palette Palette, opts HTMLOptions) string {
+	prefix := opts.ClassPrefix
+	if prefix == "" {
+		prefix = "term"
+	}
+	colors := opts.Colors
+	if colors.Palette == nil {
+		colors.Palette = &palette
+	}
+
+	var b strings.Builder
+	if opts.Mode == HTMLClasses {
+		if opts.Stylesheet {
+			writeHTMLStylesheet(&b, prefix, palette)
+		}
+		fmt.Fprintf(&b, "<pre class=\"%s\">", prefix)
+	} else {
+		fmt.Fprintf(&b, "<pre style=\"color:%s;background-color:%s\">",
+			cssColor(palette.Foreground), cssColor(palette.Background))
+	}
+
+	for y, line := range lines {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+
+		attrs := make([]string, len(line))
+		links := make([]string, len(line))
+		end := 0
+		for x, cell := range line {
+			attrs[x] = htmlAttributes(cell, prefix, opts.Mode, colors)
+			if uri := cell.Hyperlink.URI; uri != "" && safeLinkURI(uri) {
+				links[x] = uri
+			}
+			if attrs[x] != "" || links[x] != "" || (cell.Char != ' ' && cell.Char != 0) {
+				end = x + 1
+			}
+		}
+
+		link := ""
+		for x := 0; x < end; {
+			if links[x] != link {
+				if link != "" {
+					b.WriteString("</a>")
+				}
+				if links[x] != "" {
+					fmt.Fprintf(&b, "<a href=\"%s\">", html.EscapeString(links[x]))
+				}
+				link = links[x]
+			}
+
+			run := x
+			var text strings.Builder
+			for ; run < end && attrs[run] == attrs[x] && links[run] == link; run++ {
+				switch {
+				case line[run].WideCharSpacer:
+				case line[run].Char == 0:
+					text.WriteByte(' ')
+				default:
+					text.WriteRune(line[run].Char)
+				}
+			}
+
+			if attrs[x] == "" {
+				b.WriteString(html.EscapeString(text.String()))
+			} else {
+				fmt.Fprintf(&b, "<span%s>%s</span>", attrs[x], html.EscapeString(text.String()))
+			}
+			x = run
+		}
+		if link != "" {
+			b.WriteString("</a>")
+		}
+	}
+
+	b.WriteString("</pre>")
+	return b.String()
+}
+
+// htmlAttributes returns the class and style attributes of the span for a
+// cell, or "" for a cell in the default style
+func htmlAttributes(cell Cell, prefix string, mode HTMLMode, colors ColorOptions) string {
+	fg, bg, fgIndex, bgIndex := cell.effectiveColors(colors)
+
+	var classes, styles []string
+	color := func(kind, property string, c RGB, index, defaultIndex uint16) {
+		if index == defaultIndex {
+			return
+		}
+		if mode == HTMLClasses {
+			switch {
+			case index < 16:
+				classes = append(classes, fmt.Sprintf("%s-%s-%d", prefix, kind, index))
+				return
+			case index == IndexForeground:
+				classes = append(classes, prefix+"-"+kind+"-fg")
+				return
+			case index == IndexBackground:
+				classes = append(classes, prefix+"-"+kind+"-bg")
+				return
+			}
+		}
+		styles = append(styles, property+":"+cssColor(c))
+	}
+	color("fg", "color", fg, fgIndex, IndexForeground)
+	color("bg", "background-color", bg, bgIndex, IndexBackground)
+
+	attr := func(set bool, name, style string) {
+		if !set {
+			return
+		}
+		if mode == HTMLClasses {
+			classes = append(classes, prefix+"-"+name)
+		} else {
+			styles = append(styles, style)
+		}
+	}
+	attr(cell.Bold, "bold", "font-weight:bold")
+	attr(cell.Italic, "italic", "font-style:italic")
+	attr(cell.Underline, "underline", "text-decoration:underline")
+	attr(cell.Strikeout, "strikeout", "text-decoration:line-through")
+	if mode != HTMLClasses && cell.Underline && cell.Strikeout {
+		styles = append(styles[:len(styles)-2], "text-decoration:underline line-through")
+	}
+
+	var b strings.Builder
+	if len(classes) > 0 {
+		fmt.Fprintf(&b, " class=\"%s\"", strings.Join(classes, " "))
+	}
+	if len(styles) > 0 {
+		fmt.Fprintf(&b, " style=\"%s\"", strings.Join(styles, ";"))
+	}
+	return b.String()
+}
+
+// writeHTMLStylesheet defines the classes used by HTMLClasses for palette
+func writeHTMLStylesheet(b *strings.Builder, prefix string, palette Palette) {
+	b.WriteString("<style>\n")
+	fmt.Fprintf(b, ".%s{color:%s;background-color:%s}\n",
+		prefix, cssColor(palette.Foreground), cssColor(palette.Background))
+	for i, c := range palette.ANSI {
+		fmt.Fprintf(b, ".%s-fg-%d{color:%s}\n", prefix, i, cssColor(c))
+		fmt.Fprintf(b, ".%s-bg-%d{background-color:%s}\n", prefix, i, cssColor(c))
+	}
+	fmt.Fprintf(b, ".%s-fg-bg{color:%s}\n", prefix, cssColor(palette.Background))
+	fmt.Fprintf(b, ".%s-bg-fg{background-color:%s}\n", prefix, cssColor(palette.Foreground))
+	fmt.Fprintf(b, ".%s-bold{font-weight:bold}\n", prefix)
+	fmt.Fprintf(b, ".%s-italic{font-style:italic}\n", prefix)
+	fmt.Fprintf(b, ".%s-underline{text-decoration:underline}\n", prefix)
+	fmt.Fprintf(b, ".%s-strikeout{text-decoration:line-through}\n", prefix)
+	fmt.Fprintf(b, ".%s-underline.%s-strikeout{text-decoration:underline line-through}\n", prefix, prefix)
+	b.WriteString("</style>\n")
+}
+
+func cssColor(c RGB) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
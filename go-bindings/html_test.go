@@ -0,0 +1,85 @@
+package alacritty
+
+import (
+	"strings"
+	"testing"
+)
+
+func plainCell(c rune) Cell {
+	return Cell{
+		Char:    c,
+		FgColor: RGB{R: 255, G: 255, B: 255},
+		FgIndex: IndexForeground,
+		BgIndex: IndexBackground,
+	}
+}
+
+func TestHTMLInline(t *testing.T) {
+	term := NewTerminal(20, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	if _, err := term.Write([]byte("a<b \x1b[1;31mred\x1b[0m")); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	out, err := term.HTML(HTMLOptions{})
+	if err != nil {
+		t.Fatalf("Failed to export HTML: %v", err)
+	}
+
+	expected := `<pre style="color:#ffffff;background-color:#000000">` +
+		`a&lt;b <span style="color:#ff0000;font-weight:bold">red</span>` + "\n</pre>"
+	if out != expected {
+		t.Errorf("HTML:\nexpected %q\ngot      %q", expected, out)
+	}
+}
+
+func TestHTMLClasses(t *testing.T) {
+	red := plainCell('r')
+	red.FgColor, red.FgIndex = RGB{R: 255}, 1
+	inverse := plainCell('i')
+	inverse.Inverse = true
+	direct := plainCell('d')
+	direct.FgColor, direct.FgIndex = RGB{R: 1, G: 2, B: 3}, IndexDirect
+	direct.Underline = true
+
+	lines := [][]Cell{{red, inverse, direct, plainCell(' ')}}
+	out := renderHTML(lines, DefaultPalette(), HTMLOptions{Mode: HTMLClasses, ClassPrefix: "t"})
+
+	expected := `<pre class="t">` +
+		`<span class="t-fg-1">r</span>` +
+		`<span class="t-fg-bg t-bg-fg">i</span>` +
+		`<span class="t-underline" style="color:#010203">d</span>` +
+		`</pre>`
+	if out != expected {
+		t.Errorf("HTML:\nexpected %q\ngot      %q", expected, out)
+	}
+}
+
+func TestHTMLStylesheet(t *testing.T) {
+	out := renderHTML([][]Cell{{plainCell('x')}}, DefaultPalette(), HTMLOptions{Mode: HTMLClasses, Stylesheet: true})
+
+	for _, rule := range []string{
+		".term{color:#ffffff;background-color:#000000}",
+		".term-fg-1{color:#ff0000}",
+		".term-bg-12{background-color:#8080ff}",
+		".term-bold{font-weight:bold}",
+	} {
+		if !strings.Contains(out, rule) {
+			t.Errorf("Stylesheet is missing %q", rule)
+		}
+	}
+}
+
+func TestHTMLUnderlineAndStrikeout(t *testing.T) {
+	cell := plainCell('x')
+	cell.Underline, cell.Strikeout = true, true
+
+	attrs := htmlAttributes(cell, "term", HTMLInline, ColorOptions{})
+	if attrs != ` style="text-decoration:underline line-through"` {
+		t.Errorf("Unexpected attributes %q", attrs)
+	}
+}
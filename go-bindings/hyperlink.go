@@ -0,0 +1,68 @@
+package alacritty
+
+import (
+	"net/url"
+	"strings"
+)
+
+// HyperlinkSpan is a run of cells on one row that link to the same target
+type HyperlinkSpan struct {
+	Hyperlink
+	Row uint32
+	// Start is the first column and End the column after the last
+	Start, End uint32
+}
+
+// Hyperlinks lists the OSC 8 links on the screen, row by row
+func (t *Terminal) Hyperlinks() ([]HyperlinkSpan, error) {
+	s, err := t.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return s.Hyperlinks(), nil
+}
+
+// Hyperlinks lists the OSC 8 links on the screen, row by row. A link that
+// wraps over several rows has a span on each of them.
+func (s *Screen) Hyperlinks() []HyperlinkSpan {
+	var spans []HyperlinkSpan
+	for y, line := range s.lines {
+		for x := 0; x < len(line); {
+			link := line[x].Hyperlink
+			if link == (Hyperlink{}) {
+				x++
+				continue
+			}
+
+			start := x
+			for x < len(line) && line[x].Hyperlink == link {
+				x++
+			}
+			spans = append(spans, HyperlinkSpan{
+				Hyperlink: link,
+				Row:       uint32(y),
+				Start:     uint32(start),
+				End:       uint32(x),
+			})
+		}
+	}
+	return spans
+}
+
+// safeLinkSchemes are the URI schemes exported as links to HTML, so that
+// terminal output cannot inject scripts into a page
+var safeLinkSchemes = []string{"http", "https", "ftp", "file", "mailto"}
+
+// safeLinkURI reports whether uri may be used as a link target in HTML
+func safeLinkURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	for _, scheme := range safeLinkSchemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package alacritty
+
+import (
+	"reflect"
+	"testing"
+)
+
+func linkedLine(text string, link Hyperlink, from, to int) []Cell {
+	var line []Cell
+	for x, c := range text {
+		cell := plainCell(c)
+		if x >= from && x < to {
+			cell.Hyperlink = link
+		}
+		line = append(line, cell)
+	}
+	return line
+}
+
+func TestTerminalHyperlinks(t *testing.T) {
+	term := NewTerminal(20, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	term.Write([]byte("see \x1b]8;id=m;file:///src/main.go\x1b\\main.go\x1b]8;;\x1b\\ ok"))
+
+	cell, err := term.GetCell(4, 0)
+	if err != nil {
+		t.Fatalf("GetCell: %v", err)
+	}
+	want := Hyperlink{ID: "m", URI: "file:///src/main.go"}
+	if cell.Hyperlink != want {
+		t.Errorf("Expected %+v, got %+v", want, cell.Hyperlink)
+	}
+
+	spans, err := term.Hyperlinks()
+	if err != nil {
+		t.Fatalf("Hyperlinks: %v", err)
+	}
+	expected := []HyperlinkSpan{{Hyperlink: want, Row: 0, Start: 4, End: 11}}
+	if !reflect.DeepEqual(spans, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, spans)
+	}
+}
+
+func TestScreenHyperlinks(t *testing.T) {
+	a := Hyperlink{ID: "a", URI: "https://example.com/a"}
+	b := Hyperlink{ID: "b", URI: "https://example.com/b"}
+	line := linkedLine("ab cd", a, 0, 2)
+	line[3].Hyperlink, line[4].Hyperlink = b, b
+	s := &Screen{lines: [][]Cell{linkedLine("plain", a, 0, 0), line}}
+
+	expected := []HyperlinkSpan{
+		{Hyperlink: a, Row: 1, Start: 0, End: 2},
+		{Hyperlink: b, Row: 1, Start: 3, End: 5},
+	}
+	if spans := s.Hyperlinks(); !reflect.DeepEqual(spans, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, spans)
+	}
+}
+
+func TestHTMLHyperlinks(t *testing.T) {
+	safe := Hyperlink{URI: "https://example.com/?a=1&b=2"}
+	lines := [][]Cell{
+		linkedLine("go to x", safe, 6, 7),
+		linkedLine("bad", Hyperlink{URI: "javascript:alert(1)"}, 0, 3),
+	}
+	lines[0][6].Bold = true
+
+	out := renderHTML(lines, DefaultPalette(), HTMLOptions{})
+	expected := `<pre style="color:#ffffff;background-color:#000000">go to ` +
+		`<a href="https://example.com/?a=1&amp;b=2"><span style="font-weight:bold">x</span></a>` +
+		"\nbad</pre>"
+	if out != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestEncodeANSIHyperlinks(t *testing.T) {
+	link := Hyperlink{ID: "1", URI: "https://example.com"}
+	lines := [][]Cell{linkedLine("a link", link, 2, 6)}
+
+	s := &Screen{cols: 6, rows: 1, lines: lines, cursorX: 6, pen: plainCell(' '), modes: defaultModes}
+	out := string(encodeANSI(s, false))
+	expected := "\x1b[?7h\x1b[H\x1b[2Ja \x1b]8;id=1;https://example.com\x1b\\link\x1b]8;;\x1b\\\x1b[1;7H"
+	if out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
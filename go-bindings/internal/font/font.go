@@ -0,0 +1,247 @@
+// Package font holds the embedded monospace bitmap font used to rasterize
+// terminal screens without a font renderer.
+//
+// Glyphs are 5x8 pixels drawn inside a 6x10 cell, leaving a column of
+// spacing on the right, a blank row at the top and a row below the
+// descenders for underlines.
+package font
+
+const (
+	// CellWidth is the width of a character cell in pixels
+	CellWidth = 6
+	// CellHeight is the height of a character cell in pixels
+	CellHeight = 10
+
+	// UnderlineRow is the cell row used for underlines
+	UnderlineRow = 9
+	// StrikeoutRow is the cell row used for strikeouts
+	StrikeoutRow = 5
+
+	glyphTop = 1
+)
+
+// Bitmap is a monochrome glyph image of CellWidth x CellHeight pixels, one
+// uint8 per row with bit 0 as the leftmost pixel
+type Bitmap [CellHeight]uint8
+
+// Set reports whether the pixel at (x, y) is set
+func (b *Bitmap) Set(x, y int) bool {
+	if x < 0 || x >= CellWidth || y < 0 || y >= CellHeight {
+		return false
+	}
+	return b[y]&(1<<uint(x)) != 0
+}
+
+// Glyph returns the bitmap for r. The second return value is false when the
+// font has no glyph for r, in which case a replacement box is returned.
+func Glyph(r rune) (Bitmap, bool) {
+	switch {
+	case r == 0:
+		return Bitmap{}, true
+	case r >= 0x20 && r <= 0x7e:
+		return fromColumns(ascii[r-0x20]), true
+	}
+
+	if b, ok := blockGlyph(r); ok {
+		return b, true
+	}
+	return replacement(), false
+}
+
+// fromColumns converts a glyph stored as five column bytes (bit 0 at the
+// top) into a row-major bitmap
+func fromColumns(cols [5]uint8) Bitmap {
+	var b Bitmap
+	for x, col := range cols {
+		for y := 0; y < 8; y++ {
+			if col&(1<<uint(y)) != 0 {
+				b[glyphTop+y] |= 1 << uint(x)
+			}
+		}
+	}
+	return b
+}
+
+func replacement() Bitmap {
+	var b Bitmap
+	for y := glyphTop; y < glyphTop+7; y++ {
+		if y == glyphTop || y == glyphTop+6 {
+			b[y] = 0x1f
+		} else {
+			b[y] = 0x11
+		}
+	}
+	return b
+}
+
+// Line segments reaching from the cell center towards each edge
+const (
+	segUp = 1 << iota
+	segDown
+	segLeft
+	segRight
+)
+
+// blockGlyph synthesizes the light box drawing characters and the common
+// block elements so that TUI borders connect across cells
+func blockGlyph(r rune) (Bitmap, bool) {
+	var b Bitmap
+
+	switch r {
+	case '█':
+		for y := range b {
+			b[y] = 0x3f
+		}
+		return b, true
+	case '▀':
+		for y := 0; y < CellHeight/2; y++ {
+			b[y] = 0x3f
+		}
+		return b, true
+	case '▄':
+		for y := CellHeight / 2; y < CellHeight; y++ {
+			b[y] = 0x3f
+		}
+		return b, true
+	}
+
+	segs, ok := map[rune]int{
+		'─': segLeft | segRight,
+		'│': segUp | segDown,
+		'┌': segDown | segRight,
+		'┐': segDown | segLeft,
+		'└': segUp | segRight,
+		'┘': segUp | segLeft,
+		'├': segUp | segDown | segRight,
+		'┤': segUp | segDown | segLeft,
+		'┬': segDown | segLeft | segRight,
+		'┴': segUp | segLeft | segRight,
+		'┼': segUp | segDown | segLeft | segRight,
+		'╭': segDown | segRight,
+		'╮': segDown | segLeft,
+		'╰': segUp | segRight,
+		'╯': segUp | segLeft,
+	}[r]
+	if !ok {
+		return b, false
+	}
+
+	const cx, cy = 2, CellHeight / 2
+	if segs&segUp != 0 {
+		for y := 0; y <= cy; y++ {
+			b[y] |= 1 << cx
+		}
+	}
+	if segs&segDown != 0 {
+		for y := cy; y < CellHeight; y++ {
+			b[y] |= 1 << cx
+		}
+	}
+	if segs&segLeft != 0 {
+		b[cy] |= (1 << (cx + 1)) - 1
+	}
+	if segs&segRight != 0 {
+		b[cy] |= 0x3f &^ ((1 << cx) - 1)
+	}
+	return b, true
+}
+
+// ascii holds the printable ASCII range 0x20-0x7e as five column bytes per
+// glyph, bit 0 at the top
+var ascii = [95][5]uint8{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5f, 0x00, 0x00}, // '!'
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // '"'
+	{0x14, 0x7f, 0x14, 0x7f, 0x14}, // '#'
+	{0x24, 0x2a, 0x7f, 0x2a, 0x12}, // '$'
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // '%'
+	{0x36, 0x49, 0x56, 0x20, 0x50}, // '&'
+	{0x00, 0x00, 0x07, 0x00, 0x00}, // '\''
+	{0x00, 0x1c, 0x22, 0x41, 0x00}, // '('
+	{0x00, 0x41, 0x22, 0x1c, 0x00}, // ')'
+	{0x2a, 0x1c, 0x7f, 0x1c, 0x2a}, // '*'
+	{0x08, 0x08, 0x3e, 0x08, 0x08}, // '+'
+	{0x00, 0x80, 0x70, 0x30, 0x00}, // ','
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // '-'
+	{0x00, 0x00, 0x60, 0x60, 0x00}, // '.'
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // '/'
+	{0x3e, 0x51, 0x49, 0x45, 0x3e}, // '0'
+	{0x00, 0x42, 0x7f, 0x40, 0x00}, // '1'
+	{0x72, 0x49, 0x49, 0x49, 0x46}, // '2'
+	{0x21, 0x41, 0x49, 0x4d, 0x33}, // '3'
+	{0x18, 0x14, 0x12, 0x7f, 0x10}, // '4'
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // '5'
+	{0x3c, 0x4a, 0x49, 0x49, 0x31}, // '6'
+	{0x41, 0x21, 0x11, 0x09, 0x07}, // '7'
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // '8'
+	{0x46, 0x49, 0x49, 0x29, 0x1e}, // '9'
+	{0x00, 0x00, 0x36, 0x00, 0x00}, // ':'
+	{0x00, 0x40, 0x34, 0x00, 0x00}, // ';'
+	{0x00, 0x08, 0x14, 0x22, 0x41}, // '<'
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // '='
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // '>'
+	{0x02, 0x01, 0x59, 0x09, 0x06}, // '?'
+	{0x3e, 0x41, 0x5d, 0x59, 0x4e}, // '@'
+	{0x7c, 0x12, 0x11, 0x12, 0x7c}, // 'A'
+	{0x7f, 0x49, 0x49, 0x49, 0x36}, // 'B'
+	{0x3e, 0x41, 0x41, 0x41, 0x22}, // 'C'
+	{0x7f, 0x41, 0x41, 0x41, 0x3e}, // 'D'
+	{0x7f, 0x49, 0x49, 0x49, 0x41}, // 'E'
+	{0x7f, 0x09, 0x09, 0x09, 0x01}, // 'F'
+	{0x3e, 0x41, 0x41, 0x51, 0x73}, // 'G'
+	{0x7f, 0x08, 0x08, 0x08, 0x7f}, // 'H'
+	{0x00, 0x41, 0x7f, 0x41, 0x00}, // 'I'
+	{0x20, 0x40, 0x41, 0x3f, 0x01}, // 'J'
+	{0x7f, 0x08, 0x14, 0x22, 0x41}, // 'K'
+	{0x7f, 0x40, 0x40, 0x40, 0x40}, // 'L'
+	{0x7f, 0x02, 0x1c, 0x02, 0x7f}, // 'M'
+	{0x7f, 0x04, 0x08, 0x10, 0x7f}, // 'N'
+	{0x3e, 0x41, 0x41, 0x41, 0x3e}, // 'O'
+	{0x7f, 0x09, 0x09, 0x09, 0x06}, // 'P'
+	{0x3e, 0x41, 0x51, 0x21, 0x5e}, // 'Q'
+	{0x7f, 0x09, 0x19, 0x29, 0x46}, // 'R'
+	{0x26, 0x49, 0x49, 0x49, 0x32}, // 'S'
+	{0x01, 0x01, 0x7f, 0x01, 0x01}, // 'T'
+	{0x3f, 0x40, 0x40, 0x40, 0x3f}, // 'U'
+	{0x1f, 0x20, 0x40, 0x20, 0x1f}, // 'V'
+	{0x3f, 0x40, 0x38, 0x40, 0x3f}, // 'W'
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // 'X'
+	{0x03, 0x04, 0x78, 0x04, 0x03}, // 'Y'
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // 'Z'
+	{0x00, 0x7f, 0x41, 0x41, 0x00}, // '['
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // '\\'
+	{0x00, 0x41, 0x41, 0x7f, 0x00}, // ']'
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // '^'
+	{0x80, 0x80, 0x80, 0x80, 0x80}, // '_'
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // '`'
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // 'a'
+	{0x7f, 0x48, 0x44, 0x44, 0x38}, // 'b'
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // 'c'
+	{0x38, 0x44, 0x44, 0x48, 0x7f}, // 'd'
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // 'e'
+	{0x08, 0x7e, 0x09, 0x01, 0x02}, // 'f'
+	{0x18, 0xa4, 0xa4, 0xa4, 0x7c}, // 'g'
+	{0x7f, 0x08, 0x04, 0x04, 0x78}, // 'h'
+	{0x00, 0x44, 0x7d, 0x40, 0x00}, // 'i'
+	{0x40, 0x80, 0x84, 0x7d, 0x00}, // 'j'
+	{0x7f, 0x10, 0x28, 0x44, 0x00}, // 'k'
+	{0x00, 0x41, 0x7f, 0x40, 0x00}, // 'l'
+	{0x7c, 0x04, 0x18, 0x04, 0x78}, // 'm'
+	{0x7c, 0x08, 0x04, 0x04, 0x78}, // 'n'
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // 'o'
+	{0xfc, 0x24, 0x24, 0x24, 0x18}, // 'p'
+	{0x18, 0x24, 0x24, 0x24, 0xfc}, // 'q'
+	{0x7c, 0x08, 0x04, 0x04, 0x08}, // 'r'
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // 's'
+	{0x04, 0x3f, 0x44, 0x40, 0x20}, // 't'
+	{0x3c, 0x40, 0x40, 0x20, 0x7c}, // 'u'
+	{0x1c, 0x20, 0x40, 0x20, 0x1c}, // 'v'
+	{0x3c, 0x40, 0x30, 0x40, 0x3c}, // 'w'
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // 'x'
+	{0x1c, 0xa0, 0xa0, 0xa0, 0x7c}, // 'y'
+	{0x44, 0x64, 0x54, 0x4c, 0x44}, // 'z'
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // '{'
+	{0x00, 0x00, 0x7f, 0x00, 0x00}, // '|'
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // '}'
+	{0x08, 0x04, 0x08, 0x10, 0x08}, // '~'
+}
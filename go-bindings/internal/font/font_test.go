@@ -0,0 +1,60 @@
+package font
+
+import "testing"
+
+func TestGlyphPrintableASCII(t *testing.T) {
+	for r := rune(0x21); r <= 0x7e; r++ {
+		glyph, ok := Glyph(r)
+		if !ok {
+			t.Errorf("Glyph %q: expected a glyph", r)
+			continue
+		}
+
+		set := false
+		for y := 0; y < CellHeight; y++ {
+			for x := 0; x < CellWidth; x++ {
+				set = set || glyph.Set(x, y)
+			}
+		}
+		if !set {
+			t.Errorf("Glyph %q is blank", r)
+		}
+	}
+}
+
+func TestGlyphLeavesSpacingColumn(t *testing.T) {
+	for r := rune(0x20); r <= 0x7e; r++ {
+		glyph, _ := Glyph(r)
+		for y := 0; y < CellHeight; y++ {
+			if glyph.Set(CellWidth-1, y) {
+				t.Errorf("Glyph %q draws into the spacing column at row %d", r, y)
+			}
+		}
+	}
+}
+
+func TestGlyphReplacement(t *testing.T) {
+	glyph, ok := Glyph('中')
+	if ok {
+		t.Fatal("Expected no glyph for a CJK character")
+	}
+	if glyph != replacement() {
+		t.Error("Expected the replacement box for a missing glyph")
+	}
+}
+
+func TestBoxDrawingConnects(t *testing.T) {
+	horizontal, _ := Glyph('─')
+	vertical, _ := Glyph('│')
+
+	for x := 0; x < CellWidth; x++ {
+		if !horizontal.Set(x, CellHeight/2) {
+			t.Errorf("'─' has a gap at column %d", x)
+		}
+	}
+	for y := 0; y < CellHeight; y++ {
+		if !vertical.Set(2, y) {
+			t.Errorf("'│' has a gap at row %d", y)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package alacritty
+
+/*
+#include "alacritty_ffi.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"unsafe"
+)
+
+// CursorShape is the shape the cursor is drawn with
+type CursorShape uint8
+
+// Cursor shapes
+const (
+	CursorBlock       CursorShape = C.CURSOR_SHAPE_BLOCK
+	CursorUnderline   CursorShape = C.CURSOR_SHAPE_UNDERLINE
+	CursorBeam        CursorShape = C.CURSOR_SHAPE_BEAM
+	CursorHollowBlock CursorShape = C.CURSOR_SHAPE_HOLLOW_BLOCK
+	CursorHidden      CursorShape = C.CURSOR_SHAPE_HIDDEN
+)
+
+// CursorStyle is the shape and blinking of the cursor
+type CursorStyle struct {
+	Shape    CursorShape
+	Blinking bool
+}
+
+// OSC52Policy controls which clipboard operations applications may request
+// with OSC 52
+type OSC52Policy uint8
+
+// OSC 52 policies
+const (
+	// OSC52Deny ignores clipboard requests
+	OSC52Deny OSC52Policy = C.OSC52_DISABLED
+	// OSC52WriteOnly lets applications copy to the clipboard
+	OSC52WriteOnly OSC52Policy = C.OSC52_ONLY_COPY
+	// OSC52ReadOnly lets applications read the clipboard
+	OSC52ReadOnly OSC52Policy = C.OSC52_ONLY_PASTE
+	// OSC52ReadWrite lets applications copy to and read the clipboard
+	OSC52ReadWrite OSC52Policy = C.OSC52_COPY_PASTE
+)
+
+// Option configures a terminal created by New
+type Option func(*options)
+
+type options struct {
+	c                   C.CTerminalOptions
+	semanticEscapeChars *string
+	onWorkingDirectory  func(dir string)
+	clipboard           clipboard
+	replies             io.Writer
+}
+
+// WithScrollback sets the number of lines kept in the scrollback history,
+// 10000 by default
+func WithScrollback(lines uint32) Option {
+	return func(o *options) {
+		o.c.scrollback_lines = C.uint32_t(lines)
+	}
+}
+
+// WithPalette sets the colors that named and indexed colors resolve to,
+// DefaultPalette by default
+func WithPalette(p Palette) Option {
+	return func(o *options) {
+		colors := append(p.ANSI[:], p.Foreground, p.Background)
+		for i, c := range colors {
+			o.c.palette[i] = C.CRgb{r: C.uint8_t(c.R), g: C.uint8_t(c.G), b: C.uint8_t(c.B)}
+		}
+	}
+}
+
+// WithCursorStyle sets the cursor style used until an application changes
+// it with DECSCUSR, a steady block by default
+func WithCursorStyle(style CursorStyle) Option {
+	return func(o *options) {
+		o.c.cursor_shape = C.uint8_t(style.Shape)
+		o.c.cursor_blinking = cBool(style.Blinking)
+	}
+}
+
+// WithSemanticEscapeChars sets the characters that end a word for
+// semantic selection
+func WithSemanticEscapeChars(chars string) Option {
+	return func(o *options) {
+		o.semanticEscapeChars = &chars
+	}
+}
+
+// WithKittyKeyboard enables the kitty keyboard protocol, which is disabled
+// by default
+func WithKittyKeyboard(enabled bool) Option {
+	return func(o *options) {
+		o.c.kitty_keyboard = cBool(enabled)
+	}
+}
+
+// WithOSC52 sets the clipboard operations allowed through OSC 52 to the
+// clipboard of WithClipboard, OSC52WriteOnly by default
+func WithOSC52(policy OSC52Policy) Option {
+	return func(o *options) {
+		o.c.osc52 = C.uint8_t(policy)
+	}
+}
+
+// WithMaxSize sets the largest size New and Resize accept, 4096x4096 by
+// default. Zero removes the limit.
+func WithMaxSize(cols, rows uint32) Option {
+	return func(o *options) {
+		o.c.max_columns = C.uint32_t(cols)
+		o.c.max_lines = C.uint32_t(rows)
+	}
+}
+
+// WithWorkingDirectoryFunc sets a function that Write calls after the
+// working directory returned by WorkingDirectory changed. It is called
+// without holding the terminal lock, so it may use the terminal, but calls
+// from concurrent writes may run in any order.
+func WithWorkingDirectoryFunc(fn func(dir string)) Option {
+	return func(o *options) {
+		o.onWorkingDirectory = fn
+	}
+}
+
+// WithClipboard passes the OSC 52 requests allowed by the WithOSC52 policy
+// to c. Without a clipboard, requests are ignored.
+func WithClipboard(c Clipboard) Option {
+	return func(o *options) {
+		o.clipboard.c = c
+	}
+}
+
+// WithClipboardRoutes sets the selection passed to the clipboard for each
+// selection an application may name. Requests for other selections are
+// ignored. By default the clipboard and primary selection are passed
+// as-is and SelectionSelect as the clipboard.
+func WithClipboardRoutes(routes map[Selection]Selection) Option {
+	return func(o *options) {
+		o.clipboard.routes = routes
+	}
+}
+
+// WithClipboardLimit sets the size in bytes of the largest text that
+// applications may store or load, DefaultClipboardLimit by default. Stores
+// over the limit are dropped by the library before they are decoded.
+func WithClipboardLimit(bytes int) Option {
+	return func(o *options) {
+		o.clipboard.limit = bytes
+	}
+}
+
+// WithReplyWriter sets the writer, normally the pty, that receives the
+// answers the terminal sends to the application, such as those to color
+// queries and clipboard loads. Write returns the errors of w. Without a
+// reply writer, requests that need an answer are ignored.
+func WithReplyWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.replies = w
+	}
+}
+
+func cBool(b bool) C.uint8_t {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// New creates a terminal with the specified dimensions. It fails with
+// ErrInvalidSize if either dimension is zero or exceeds the maximum size,
+// and with ErrPoisoned if the library panics creating the terminal.
+func New(cols, rows uint32, opts ...Option) (*Terminal, error) {
+	o := options{clipboard: clipboard{routes: defaultClipboardRoutes, limit: DefaultClipboardLimit}}
+	C.terminal_default_options(&o.c)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.clipboard.c == nil {
+		// Nothing would take the requests from the library
+		o.c.osc52 = C.OSC52_DISABLED
+	}
+	// The library drops stores over the limit instead of queueing them
+	o.c.clipboard_limit = C.uint32_t(min(max(int64(o.clipboard.limit), 0), math.MaxUint32))
+
+	maxCols, maxRows := uint32(o.c.max_columns), uint32(o.c.max_lines)
+	if cols == 0 || rows == 0 {
+		detail := fmt.Sprintf("invalid size %dx%d", cols, rows)
+		return nil, &Error{Op: "New", Code: CodeInvalidSize, Detail: detail}
+	}
+	if (maxCols != 0 && cols > maxCols) || (maxRows != 0 && rows > maxRows) {
+		detail := fmt.Sprintf("size %dx%d exceeds the maximum of %dx%d", cols, rows, maxCols, maxRows)
+		return nil, &Error{Op: "New", Code: CodeInvalidSize, Detail: detail}
+	}
+
+	if o.semanticEscapeChars != nil {
+		chars := C.CString(*o.semanticEscapeChars)
+		defer C.free(unsafe.Pointer(chars))
+		o.c.semantic_escape_chars = chars
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	ptr := C.terminal_new_with_options(C.uint32_t(cols), C.uint32_t(rows), &o.c)
+	if ptr == nil {
+		// The size was checked above, so creating the terminal panicked
+		return nil, lastError("New", C.ERROR_POISONED)
+	}
+
+	term := &Terminal{ptr: ptr, onWorkingDirectory: o.onWorkingDirectory, replies: o.replies}
+	if o.clipboard.c != nil {
+		term.clipboard = &o.clipboard
+	}
+	runtime.SetFinalizer(term, (*Terminal).Close)
+	return term, nil
+}
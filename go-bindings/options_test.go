@@ -0,0 +1,60 @@
+package alacritty
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewValidatesSize(t *testing.T) {
+	for _, size := range [][2]uint32{{0, 24}, {80, 0}, {5000, 24}} {
+		if _, err := New(size[0], size[1]); !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("New(%d, %d): expected ErrInvalidSize, got %v", size[0], size[1], err)
+		}
+	}
+	if _, err := New(120, 24, WithMaxSize(100, 50)); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Expected ErrInvalidSize above the maximum size, got %v", err)
+	}
+
+	term, err := New(80, 24, WithMaxSize(100, 50))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+	if err := term.Resize(200, 24); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Expected ErrInvalidSize resizing above the maximum size, got %v", err)
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	palette := DefaultPalette()
+	palette.ANSI[1] = RGB{R: 0xcc, G: 0x24, B: 0x1d}
+	palette.Foreground = RGB{R: 0xeb, G: 0xdb, B: 0xb2}
+
+	term, err := New(10, 3,
+		WithScrollback(2),
+		WithPalette(palette),
+		WithCursorStyle(CursorStyle{Shape: CursorBeam, Blinking: true}),
+		WithSemanticEscapeChars(" /"),
+		WithKittyKeyboard(true),
+		WithOSC52(OSC52Deny),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+
+	term.Write([]byte("\x1b[31mred\x1b[0m plain\r\n1\r\n2\r\n3\r\n4\r\n5"))
+	if size, _ := term.HistorySize(); size != 2 {
+		t.Errorf("Expected 2 lines of scrollback, got %d", size)
+	}
+	line, err := term.GetHistoryLine(0)
+	if err != nil {
+		t.Fatalf("GetHistoryLine: %v", err)
+	}
+	if line[0].FgColor != palette.ANSI[1] {
+		t.Errorf("Expected red from the palette, got %+v", line[0].FgColor)
+	}
+	if line[4].FgColor != palette.Foreground {
+		t.Errorf("Expected the palette foreground, got %+v", line[4].FgColor)
+	}
+}
@@ -0,0 +1,120 @@
+package alacritty
+
+/*
+#include "alacritty_ffi.h"
+*/
+import "C"
+import (
+	"runtime"
+
+	"github.com/example/alacritty-go/color"
+)
+
+// Palette is the table of colors used to resolve named and indexed colors
+type Palette = color.Palette
+
+// DefaultPalette returns the colors the terminal uses when no theme is set
+func DefaultPalette() Palette {
+	return color.DefaultPalette()
+}
+
+// Colors is the color table in use, including the changes applications
+// made with OSC 4, 10, 11 and 12
+type Colors struct {
+	// Palette holds the 16 ANSI colors and the default colors
+	Palette
+	// Indexed holds the 256 indexed colors, starting with Palette.ANSI
+	Indexed [256]RGB
+	// Cursor is the cursor color, the foreground unless set with OSC 12
+	Cursor RGB
+}
+
+// Colors returns the color table in use. Indexed colors without a palette
+// entry default to the xterm 256 color table.
+func (t *Terminal) Colors() (Colors, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.colors()
+}
+
+func (t *Terminal) colors() (Colors, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return Colors{}, ErrClosed
+	}
+
+	var table [C.COLOR_COUNT]C.CRgb
+	result := C.terminal_get_colors(t.ptr, &table[0], C.size_t(len(table)))
+	if result < 0 {
+		return Colors{}, lastError("Colors", result)
+	}
+
+	rgb := func(i int) RGB {
+		return RGB{R: uint8(table[i].r), G: uint8(table[i].g), B: uint8(table[i].b)}
+	}
+	var colors Colors
+	for i := range colors.Indexed {
+		colors.Indexed[i] = rgb(i)
+	}
+	copy(colors.ANSI[:], colors.Indexed[:16])
+	colors.Foreground = rgb(C.COLOR_INDEX_FOREGROUND)
+	colors.Background = rgb(C.COLOR_INDEX_BACKGROUND)
+	colors.Cursor = rgb(C.COLOR_INDEX_CURSOR)
+	return colors, nil
+}
+
+// DefaultDimFactor is the brightness of dim text relative to its color,
+// the factor alacritty uses
+const DefaultDimFactor = color.DefaultDimFactor
+
+// ColorOptions controls how Cell.EffectiveColors resolves the colors a
+// cell is drawn with. The zero value only applies inverse and hidden text.
+type ColorOptions = color.Options
+
+// EffectiveColors returns the colors the cell is drawn with: bold text is
+// brightened and dim text darkened as opts asks, then inverse text swaps
+// its colors and hidden text takes the background as foreground
+func (c Cell) EffectiveColors(opts ColorOptions) (fg, bg RGB) {
+	fg, bg, _, _ = c.effectiveColors(opts)
+	return fg, bg
+}
+
+// effectiveColors is EffectiveColors with the palette entries the colors
+// come from, IndexDirect for a color that was brightened or dimmed outside
+// the palette
+func (c Cell) effectiveColors(opts ColorOptions) (fg, bg RGB, fgIndex, bgIndex uint16) {
+	fg, bg = c.FgColor, c.BgColor
+	fgIndex, bgIndex = c.FgIndex, c.BgIndex
+
+	switch {
+	case c.Dim && opts.DimForeground != nil && fgIndex == IndexForeground:
+		fg, fgIndex = *opts.DimForeground, IndexDirect
+	case c.Dim && opts.Dim != nil && fgIndex < 16:
+		fg, fgIndex = opts.Dim[fgIndex%8], IndexDirect
+	case c.Dim && opts.DimFactor > 0:
+		scale := func(v uint8) uint8 { return uint8(min(float64(v)*opts.DimFactor, 255)) }
+		fg, fgIndex = RGB{R: scale(fg.R), G: scale(fg.G), B: scale(fg.B)}, IndexDirect
+	case c.Bold && !c.Dim && opts.BoldIsBright && opts.BrightForeground != nil && fgIndex == IndexForeground:
+		fg, fgIndex = *opts.BrightForeground, IndexDirect
+	case c.Bold && !c.Dim && opts.BoldIsBright && fgIndex < 8:
+		palette := opts.Palette
+		if palette == nil {
+			defaultPalette := DefaultPalette()
+			palette = &defaultPalette
+		}
+		fgIndex += 8
+		fg = palette.ANSI[fgIndex]
+	}
+
+	if c.Inverse {
+		fg, bg = bg, fg
+		fgIndex, bgIndex = bgIndex, fgIndex
+	}
+	if c.Hidden {
+		fg, fgIndex = bg, bgIndex
+	}
+	return fg, bg, fgIndex, bgIndex
+}
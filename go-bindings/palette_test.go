@@ -0,0 +1,125 @@
+package alacritty
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScreenHTMLUsesPalette(t *testing.T) {
+	palette := DefaultPalette()
+	palette.Foreground = RGB{R: 0xaa, G: 0xbb, B: 0xcc}
+	palette.Background = RGB{R: 0x11, G: 0x22, B: 0x33}
+	s := &Screen{lines: [][]Cell{{plainCell('x')}}, palette: palette}
+
+	out := s.HTML(HTMLOptions{})
+	if !strings.HasPrefix(out, `<pre style="color:#aabbcc;background-color:#112233">`) {
+		t.Errorf("Expected the palette of the screen, got %s", out)
+	}
+}
+
+func TestDynamicColors(t *testing.T) {
+	var replies bytes.Buffer
+	term, err := New(10, 2, WithReplyWriter(&replies))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+
+	term.Write([]byte("\x1b]4;1;rgb:12/34/56\x07\x1b]11;#102030\x07\x1b]12;rgb:ff/00/00\x07"))
+	term.Write([]byte("\x1b[31mr\x1b[38;5;202mx"))
+
+	red := RGB{R: 0x12, G: 0x34, B: 0x56}
+	if cell, _ := term.GetCell(0, 0); cell.FgColor != red {
+		t.Errorf("Expected the changed red, got %+v", cell.FgColor)
+	}
+	if cell, _ := term.GetCell(1, 0); cell.FgColor != (RGB{R: 0xff, G: 0x5f, B: 0x00}) {
+		t.Errorf("Expected color 202 of the xterm table, got %+v", cell.FgColor)
+	}
+
+	colors, err := term.Colors()
+	if err != nil {
+		t.Fatalf("Colors: %v", err)
+	}
+	if colors.ANSI[1] != red || colors.Indexed[1] != red {
+		t.Errorf("Expected the changed red in the colors, got %+v", colors.ANSI[1])
+	}
+	if colors.Background != (RGB{R: 0x10, G: 0x20, B: 0x30}) || colors.Cursor != (RGB{R: 0xff, G: 0, B: 0}) {
+		t.Errorf("Unexpected background %+v or cursor %+v", colors.Background, colors.Cursor)
+	}
+
+	html, err := term.HTML(HTMLOptions{})
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	if !strings.Contains(html, "background-color:#102030") {
+		t.Errorf("Expected the changed background in the HTML, got %s", html)
+	}
+
+	term.Write([]byte("\x1b]4;1;?\x07\x1b]10;?\x1b\\"))
+	expected := "\x1b]4;1;rgb:1212/3434/5656\x07\x1b]10;rgb:ffff/ffff/ffff\x1b\\"
+	if replies.String() != expected {
+		t.Errorf("Expected answers %q, got %q", expected, replies.String())
+	}
+
+	term.Write([]byte("\x1b]104\x07"))
+	if cell, _ := term.GetCell(0, 0); cell.FgColor != DefaultPalette().ANSI[1] {
+		t.Errorf("Expected the default red after a reset, got %+v", cell.FgColor)
+	}
+}
+
+func TestEffectiveColors(t *testing.T) {
+	palette := DefaultPalette()
+	red := Cell{FgColor: palette.ANSI[1], FgIndex: 1, BgColor: palette.Background, BgIndex: IndexBackground}
+	bold, dim, inverse, hidden := red, red, red, red
+	bold.Bold, dim.Dim, inverse.Inverse, hidden.Hidden = true, true, true, true
+	boldInverse := bold
+	boldInverse.Inverse = true
+	direct := dim
+	direct.FgColor, direct.FgIndex = RGB{R: 200, G: 100, B: 50}, IndexDirect
+	plain := Cell{FgColor: palette.Foreground, FgIndex: IndexForeground, BgColor: palette.Background, BgIndex: IndexBackground}
+	boldPlain, dimPlain := plain, plain
+	boldPlain.Bold, dimPlain.Dim = true, true
+	dimForeground, brightForeground := RGB{R: 0x90, G: 0x90, B: 0x90}, RGB{R: 0xff, G: 0xff, B: 0xf0}
+
+	dimRed := RGB{R: 0x80, G: 0, B: 0}
+	for _, tc := range []struct {
+		name   string
+		cell   Cell
+		opts   ColorOptions
+		fg, bg RGB
+	}{
+		{"Plain", red, ColorOptions{BoldIsBright: true, DimFactor: 0.5}, palette.ANSI[1], palette.Background},
+		{"Bold", bold, ColorOptions{}, palette.ANSI[1], palette.Background},
+		{"Bold as bright", bold, ColorOptions{BoldIsBright: true}, palette.ANSI[9], palette.Background},
+		{"Bold inverse as bright", boldInverse, ColorOptions{BoldIsBright: true}, palette.Background, palette.ANSI[9]},
+		{"Dim", dim, ColorOptions{}, palette.ANSI[1], palette.Background},
+		{"Dim by factor", dim, ColorOptions{DimFactor: 0.5}, RGB{R: 127, G: 0, B: 0}, palette.Background},
+		{"Dim from theme", dim, ColorOptions{Dim: &[8]RGB{1: dimRed}, DimFactor: 0.5}, dimRed, palette.Background},
+		{"Dim truecolor", direct, ColorOptions{Dim: &[8]RGB{1: dimRed}, DimFactor: 0.5}, RGB{R: 100, G: 50, B: 25}, palette.Background},
+		{"Dim foreground", dimPlain, ColorOptions{DimForeground: &dimForeground, DimFactor: 0.5}, dimForeground, palette.Background},
+		{"Dim foreground by factor", dimPlain, ColorOptions{DimFactor: 0.5}, RGB{R: 127, G: 127, B: 127}, palette.Background},
+		{"Dim foreground of red", dim, ColorOptions{DimForeground: &dimForeground}, palette.ANSI[1], palette.Background},
+		{"Bright foreground", boldPlain, ColorOptions{BoldIsBright: true, BrightForeground: &brightForeground}, brightForeground, palette.Background},
+		{"Bright foreground without bold as bright", boldPlain, ColorOptions{BrightForeground: &brightForeground}, palette.Foreground, palette.Background},
+		{"Inverse", inverse, ColorOptions{}, palette.Background, palette.ANSI[1]},
+		{"Hidden", hidden, ColorOptions{}, palette.Background, palette.Background},
+	} {
+		fg, bg := tc.cell.EffectiveColors(tc.opts)
+		if fg != tc.fg || bg != tc.bg {
+			t.Errorf("%s: expected %v on %v, got %v on %v", tc.name, tc.fg, tc.bg, fg, bg)
+		}
+	}
+}
+
+func TestHTMLBoldIsBrightClass(t *testing.T) {
+	cell := plainCell('x')
+	cell.Bold, cell.FgColor, cell.FgIndex = true, DefaultPalette().ANSI[2], 2
+	out := renderHTML([][]Cell{{cell}}, DefaultPalette(), HTMLOptions{
+		Mode:   HTMLClasses,
+		Colors: ColorOptions{BoldIsBright: true},
+	})
+	if !strings.Contains(out, `class="term-fg-10 term-bold"`) {
+		t.Errorf("Expected the bright green class, got %s", out)
+	}
+}
@@ -0,0 +1,238 @@
+package alacritty
+
+/*
+#include "alacritty_ffi.h"
+*/
+import "C"
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Event is a token of a byte stream: a PrintEvent, ExecuteEvent, CSIEvent,
+// OSCEvent, DCSEvent or ESCEvent
+type Event interface {
+	event()
+}
+
+// PrintEvent is a run of printed characters
+type PrintEvent struct {
+	Text string
+}
+
+// ExecuteEvent is a C0 or C1 control, such as a line feed
+type ExecuteEvent struct {
+	Byte byte
+}
+
+// CSIEvent is a control sequence, such as SGR
+type CSIEvent struct {
+	// Params holds the parameters, each followed by the subparameters that
+	// were separated from it by colons
+	Params        [][]uint16
+	Intermediates []byte
+	Final         byte
+	// Ignored is set when the sequence had more parameters or intermediates
+	// than the parser keeps, which a terminal ignores
+	Ignored bool
+}
+
+// OSCEvent is an operating system command, such as a title change
+type OSCEvent struct {
+	// Params holds the parameters separated by semicolons. The parser keeps
+	// the first 16 and drops the rest, as alacritty does.
+	Params [][]byte
+	// BellTerminated is set when the string ended with BEL rather than ST
+	BellTerminated bool
+}
+
+// DCSEvent is a device control string, such as a sixel image
+type DCSEvent struct {
+	Params        [][]uint16
+	Intermediates []byte
+	Final         byte
+	Data          []byte
+	Ignored       bool
+	// Truncated is set when data beyond the first MiB was dropped
+	Truncated bool
+}
+
+// ESCEvent is an escape sequence, such as a charset designation
+type ESCEvent struct {
+	Intermediates []byte
+	Final         byte
+	Ignored       bool
+}
+
+func (PrintEvent) event()   {}
+func (ExecuteEvent) event() {}
+func (CSIEvent) event()     {}
+func (OSCEvent) event()     {}
+func (DCSEvent) event()     {}
+func (ESCEvent) event()     {}
+
+// Parser splits byte streams into events with the parser of alacritty,
+// without emulating a terminal. It keeps its state between calls of Parse,
+// so a sequence or character may be split across inputs. It is safe for
+// concurrent use.
+type Parser struct {
+	mu  sync.Mutex
+	ptr *C.CParser
+}
+
+// NewParser creates a parser in the ground state
+func NewParser() (*Parser, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	ptr := C.parser_new()
+	if ptr == nil {
+		// Creating a parser only fails by panicking
+		return nil, lastError("NewParser", C.ERROR_POISONED)
+	}
+
+	p := &Parser{ptr: ptr}
+	runtime.SetFinalizer(p, (*Parser).Close)
+	return p, nil
+}
+
+// Close frees the parser resources
+func (p *Parser) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ptr != nil {
+		C.parser_free(p.ptr)
+		p.ptr = nil
+		runtime.SetFinalizer(p, nil)
+	}
+}
+
+// Parse parses data and returns the events it completed. A sequence that
+// data leaves unfinished is returned by a later call. Characters are
+// returned as soon as they are complete, so one run of text may be split
+// into several PrintEvents.
+func (p *Parser) Parse(data []byte) ([]Event, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if p.ptr == nil {
+		return nil, ErrClosed
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	result := C.parser_advance(p.ptr, (*C.uint8_t)(unsafe.Pointer(&data[0])), C.size_t(len(data)))
+	if result <= 0 {
+		if result < 0 {
+			return nil, lastError("Parse", result)
+		}
+		return nil, nil
+	}
+
+	cEvents := make([]C.CParserEvent, result)
+	if result := C.parser_get_events(p.ptr, &cEvents[0], C.size_t(len(cEvents))); result < 0 {
+		return nil, lastError("Parse", result)
+	}
+	events := make([]Event, len(cEvents))
+	for i, e := range cEvents {
+		events[i] = goEvent(e)
+	}
+
+	if result := C.parser_clear_events(p.ptr); result < 0 {
+		return nil, lastError("Parse", result)
+	}
+	return events, nil
+}
+
+// Parse parses data with a new parser, calling handler for each event
+func Parse(data []byte, handler func(Event)) error {
+	p, err := NewParser()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	events, err := p.Parse(data)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		handler(e)
+	}
+	return nil
+}
+
+// goEvent copies an event out of the parser
+func goEvent(e C.CParserEvent) Event {
+	switch e.kind {
+	case C.PARSER_EVENT_PRINT:
+		return PrintEvent{Text: C.GoStringN((*C.char)(unsafe.Pointer(e.data)), C.int(e.data_len))}
+	case C.PARSER_EVENT_EXECUTE:
+		return ExecuteEvent{Byte: byte(e.action)}
+	case C.PARSER_EVENT_CSI:
+		return CSIEvent{
+			Params:        goParams(e),
+			Intermediates: goBytes(e.intermediates, e.intermediates_len),
+			Final:         byte(e.action),
+			Ignored:       e.ignore != 0,
+		}
+	case C.PARSER_EVENT_OSC:
+		// vte splits the string at semicolons into at most 16 parameters,
+		// dropping the text after the 16th, so parameters never contain a
+		// semicolon and splitting restores them
+		return OSCEvent{
+			Params:         bytes.Split(goBytes(e.data, e.data_len), []byte(";")),
+			BellTerminated: e.bell_terminated != 0,
+		}
+	case C.PARSER_EVENT_DCS:
+		return DCSEvent{
+			Params:        goParams(e),
+			Intermediates: goBytes(e.intermediates, e.intermediates_len),
+			Final:         byte(e.action),
+			Data:          goBytes(e.data, e.data_len),
+			Ignored:       e.ignore != 0,
+			Truncated:     e.truncated != 0,
+		}
+	default:
+		return ESCEvent{
+			Intermediates: goBytes(e.intermediates, e.intermediates_len),
+			Final:         byte(e.action),
+			Ignored:       e.ignore != 0,
+		}
+	}
+}
+
+// goParams copies the CSI or DCS parameters of an event
+func goParams(e C.CParserEvent) [][]uint16 {
+	if e.params_len == 0 {
+		return nil
+	}
+
+	lens := unsafe.Slice((*uint8)(unsafe.Pointer(e.param_lens)), e.params_len)
+	total := 0
+	for _, n := range lens {
+		total += int(n)
+	}
+	values := unsafe.Slice((*uint16)(unsafe.Pointer(e.params)), total)
+
+	params := make([][]uint16, len(lens))
+	for i, n := range lens {
+		params[i] = append([]uint16(nil), values[:n]...)
+		values = values[n:]
+	}
+	return params
+}
+
+// goBytes copies n bytes at p, nil if there are none
+func goBytes(p *C.uint8_t, n C.size_t) []byte {
+	if n == 0 {
+		return nil
+	}
+	return C.GoBytes(unsafe.Pointer(p), C.int(n))
+}
@@ -0,0 +1,120 @@
+package alacritty
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParserEvents(t *testing.T) {
+	p, err := NewParser()
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+	defer p.Close()
+
+	events, err := p.Parse([]byte("a\x1b[1;38:2::255:0:0mb\r\n\x1b]0;title\x07\x1b(B\x1bP1$qm\x1b\\\x1b[?25l"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	expected := []Event{
+		PrintEvent{Text: "a"},
+		CSIEvent{Params: [][]uint16{{1}, {38, 2, 0, 255, 0, 0}}, Final: 'm'},
+		PrintEvent{Text: "b"},
+		ExecuteEvent{Byte: '\r'},
+		ExecuteEvent{Byte: '\n'},
+		OSCEvent{Params: [][]byte{[]byte("0"), []byte("title")}, BellTerminated: true},
+		ESCEvent{Intermediates: []byte("("), Final: 'B'},
+		DCSEvent{Params: [][]uint16{{1}}, Intermediates: []byte("$"), Final: 'q', Data: []byte("m")},
+		// The ESC of the ST that ends the DCS string
+		ESCEvent{Final: '\\'},
+		CSIEvent{Params: [][]uint16{{25}}, Intermediates: []byte("?"), Final: 'l'},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, events)
+	}
+}
+
+func TestParserChunks(t *testing.T) {
+	p, err := NewParser()
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+	defer p.Close()
+
+	var events []Event
+	// A CSI, an OSC and a UTF-8 character split across inputs
+	for _, chunk := range []string{"\x1b[3", "1mé\xe2", "\x82\xac\x1b]2;a", "b\x1b", "\\"} {
+		chunkEvents, err := p.Parse([]byte(chunk))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		events = append(events, chunkEvents...)
+	}
+
+	expected := []Event{
+		CSIEvent{Params: [][]uint16{{31}}, Final: 'm'},
+		PrintEvent{Text: "é"},
+		PrintEvent{Text: "€"},
+		OSCEvent{Params: [][]byte{[]byte("2"), []byte("ab")}},
+		ESCEvent{Final: '\\'},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, events)
+	}
+}
+
+func TestParse(t *testing.T) {
+	var text string
+	err := Parse([]byte("\x1b[1mbold\x1b[0m plain"), func(e Event) {
+		if print, ok := e.(PrintEvent); ok {
+			text += print.Text
+		}
+	})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if text != "bold plain" {
+		t.Errorf("Expected the printed text, got %q", text)
+	}
+}
+
+func TestParserOSCParamLimit(t *testing.T) {
+	var fields []string
+	for i := 0; i < 18; i++ {
+		fields = append(fields, strconv.Itoa(i))
+	}
+
+	var events []Event
+	err := Parse([]byte("\x1b]"+strings.Join(fields, ";")+"\x07"), func(e Event) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var params [][]byte
+	for _, field := range fields[:16] {
+		params = append(params, []byte(field))
+	}
+	expected := []Event{OSCEvent{Params: params, BellTerminated: true}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected the first 16 parameters, got %#v", events)
+	}
+}
+
+func TestParserClosed(t *testing.T) {
+	p, err := NewParser()
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+	p.Close()
+	p.Close()
+
+	if _, err := p.Parse([]byte("a")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package alacritty
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"github.com/example/alacritty-go/internal/font"
+)
+
+// RenderOptions controls how the screen is rasterized
+type RenderOptions struct {
+	// Scale is the integer magnification of the bitmap font, 1 if zero
+	Scale int
+	// Padding is the margin around the grid in unscaled pixels
+	Padding int
+	// Background fills the padding around the grid
+	Background RGB
+	// Cursor draws the cursor as a block with inverted colors
+	Cursor bool
+	// Colors controls how bold, dim, inverse and hidden text is colored
+	Colors ColorOptions
+}
+
+// RenderImage rasterizes the current screen with the embedded bitmap font.
+// It returns nil if the terminal is closed.
+func (t *Terminal) RenderImage(opts RenderOptions) image.Image {
+	s, err := t.Snapshot()
+	if err != nil {
+		return nil
+	}
+	return s.RenderImage(opts)
+}
+
+// RenderPNG rasterizes the current screen and encodes it as PNG
+func (t *Terminal) RenderPNG(w io.Writer, opts RenderOptions) error {
+	s, err := t.Snapshot()
+	if err != nil {
+		return fmt.Errorf("failed to render terminal: %w", err)
+	}
+	return s.RenderPNG(w, opts)
+}
+
+// RenderImage rasterizes the snapshot with the embedded bitmap font
+func (s *Screen) RenderImage(opts RenderOptions) image.Image {
+	cursorX, cursorY := -1, -1
+	if opts.Cursor {
+		cursorX, cursorY = int(s.cursorX), int(s.cursorY)
+	}
+	return renderGrid(s.lines, s.palette, cursorX, cursorY, opts)
+}
+
+// RenderPNG rasterizes the snapshot and encodes it as PNG
+func (s *Screen) RenderPNG(w io.Writer, opts RenderOptions) error {
+	return png.Encode(w, s.RenderImage(opts))
+}
+
+// renderGrid draws lines of cells, placing the cursor at (cursorX, cursorY)
+// unless it is negative
+func renderGrid(lines [][]Cell, palette Palette, cursorX, cursorY int, opts RenderOptions) *image.RGBA {
+	colors := opts.Colors
+	if colors.Palette == nil {
+		colors.Palette = &palette
+	}
+
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	pad := opts.Padding * scale
+	cw, ch := font.CellWidth*scale, font.CellHeight*scale
+
+	cols := 0
+	for _, line := range lines {
+		cols = max(cols, len(line))
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, cols*cw+2*pad, len(lines)*ch+2*pad))
+	draw.Draw(img, img.Bounds(), image.NewUniform(rgba(opts.Background)), image.Point{}, draw.Src)
+
+	for y, line := range lines {
+		for x, cell := range line {
+			if cell.WideCharSpacer {
+				continue
+			}
+
+			fg, bg := cell.EffectiveColors(colors)
+			if x == cursorX && y == cursorY {
+				fg, bg = bg, fg
+			}
+
+			width := 1
+			if cell.WideChar {
+				width = 2
+			}
+
+			origin := image.Pt(pad+x*cw, pad+y*ch)
+			cellRect := image.Rectangle{Min: origin, Max: origin.Add(image.Pt(width*cw, ch))}
+			draw.Draw(img, cellRect, image.NewUniform(rgba(bg)), image.Point{}, draw.Src)
+
+			glyphOrigin := origin
+			if width == 2 {
+				glyphOrigin.X += cw / 2
+			}
+			drawCell(img, cell, glyphOrigin, cellRect, scale, rgba(fg))
+		}
+	}
+
+	return img
+}
+
+// drawCell draws the glyph and decorations of a cell in color fg
+func drawCell(img *image.RGBA, cell Cell, origin image.Point, cellRect image.Rectangle, scale int, fg color.RGBA) {
+	pixel := func(x, y int) {
+		p := origin.Add(image.Pt(x*scale, y*scale))
+		r := image.Rectangle{Min: p, Max: p.Add(image.Pt(scale, scale))}.Intersect(cellRect)
+		draw.Draw(img, r, image.NewUniform(fg), image.Point{}, draw.Src)
+	}
+
+	glyph, _ := font.Glyph(cell.Char)
+	for gy := 0; gy < font.CellHeight; gy++ {
+		for gx := 0; gx < font.CellWidth; gx++ {
+			if !glyph.Set(gx, gy) {
+				continue
+			}
+			pixel(gx, gy)
+			if cell.Bold {
+				pixel(gx+1, gy)
+			}
+		}
+	}
+
+	line := func(gy int) {
+		p := image.Pt(cellRect.Min.X, origin.Y+gy*scale)
+		r := image.Rectangle{Min: p, Max: image.Pt(cellRect.Max.X, p.Y+scale)}
+		draw.Draw(img, r, image.NewUniform(fg), image.Point{}, draw.Src)
+	}
+	if cell.Underline {
+		line(font.UnderlineRow)
+	}
+	if cell.Strikeout {
+		line(font.StrikeoutRow)
+	}
+}
+
+func rgba(c RGB) color.RGBA {
+	return color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+}
@@ -0,0 +1,109 @@
+package alacritty
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/example/alacritty-go/internal/font"
+)
+
+func TestRenderImageSize(t *testing.T) {
+	term := NewTerminal(80, 24)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	img := term.RenderImage(RenderOptions{Scale: 2, Padding: 4})
+	if img == nil {
+		t.Fatal("Failed to render terminal")
+	}
+
+	expected := image.Rect(0, 0, 80*font.CellWidth*2+16, 24*font.CellHeight*2+16)
+	if img.Bounds() != expected {
+		t.Errorf("Bounds: expected %v, got %v", expected, img.Bounds())
+	}
+}
+
+func TestRenderImageColors(t *testing.T) {
+	term := NewTerminal(10, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	if _, err := term.Write([]byte("\x1b[31mA\x1b[0m\x1b[7mB")); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	img := term.RenderImage(RenderOptions{})
+	if img == nil {
+		t.Fatal("Failed to render terminal")
+	}
+
+	// The left stroke of 'A' covers rows 3-7 of the first column
+	if c := img.At(0, 4); c != rgba(RGB{R: 255}) {
+		t.Errorf("Glyph pixel: expected red, got %v", c)
+	}
+	if c := img.At(0, 0); c != rgba(RGB{}) {
+		t.Errorf("Background pixel: expected black, got %v", c)
+	}
+
+	// The inverse cell has a white background
+	if c := img.At(font.CellWidth+font.CellWidth-1, 0); c != rgba(RGB{R: 255, G: 255, B: 255}) {
+		t.Errorf("Inverse background: expected white, got %v", c)
+	}
+}
+
+func TestRenderGridDecorations(t *testing.T) {
+	white := RGB{R: 255, G: 255, B: 255}
+	lines := [][]Cell{{
+		{Char: ' ', FgColor: white, Underline: true},
+		{Char: ' ', FgColor: white, Strikeout: true},
+		{Char: '中', FgColor: white, WideChar: true},
+		{Char: ' ', FgColor: white, WideCharSpacer: true},
+	}}
+
+	img := renderGrid(lines, DefaultPalette(), 1, 0, RenderOptions{})
+
+	if c := img.At(2, font.UnderlineRow); c != rgba(white) {
+		t.Errorf("Underline: expected white, got %v", c)
+	}
+	// The cursor swaps the strikeout cell to black on white
+	if c := img.At(font.CellWidth+2, font.StrikeoutRow); c != rgba(RGB{}) {
+		t.Errorf("Strikeout under cursor: expected black, got %v", c)
+	}
+	if c := img.At(font.CellWidth+2, 0); c != rgba(white) {
+		t.Errorf("Cursor background: expected white, got %v", c)
+	}
+
+	// The replacement box of the wide character is centered over both cells
+	if c := img.At(2*font.CellWidth+font.CellWidth/2, 1); c != rgba(white) {
+		t.Errorf("Wide glyph: expected white, got %v", c)
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	term := NewTerminal(20, 5)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	term.Write([]byte("Hello, World!"))
+
+	var buf bytes.Buffer
+	if err := term.RenderPNG(&buf, RenderOptions{Cursor: true}); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 20*font.CellWidth || img.Bounds().Dy() != 5*font.CellHeight {
+		t.Errorf("Unexpected PNG size %v", img.Bounds())
+	}
+}
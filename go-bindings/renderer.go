@@ -0,0 +1,266 @@
+package alacritty
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ColorProfile is the color capability of the terminal RenderDiff draws to
+type ColorProfile int
+
+const (
+	// TrueColor terminals accept 24-bit colors
+	TrueColor ColorProfile = iota
+	// ANSI256 terminals accept the 256 indexed colors
+	ANSI256
+	// ANSI16 terminals only accept the 16 basic colors
+	ANSI16
+)
+
+// DiffOptions controls RenderDiff
+type DiffOptions struct {
+	Profile ColorProfile
+	// OffsetX and OffsetY place the frame inside the host terminal, for
+	// example to draw it as a pane
+	OffsetX, OffsetY int
+}
+
+// diffGap is the length of the shortest run of unchanged cells that is
+// skipped with a cursor movement instead of being redrawn
+const diffGap = 4
+
+// RenderDiff writes the escape sequences that update a terminal showing
+// prev so that it shows next. The host terminal is expected to display prev
+// with the cursor at its position and the default graphic rendition, which
+// is also the state RenderDiff leaves it in. A nil prev, or one of another
+// size, redraws every cell.
+func RenderDiff(w io.Writer, prev, next *Screen, opts DiffOptions) error {
+	var buf bytes.Buffer
+	d := &differ{buf: &buf, opts: opts, cursorX: -1, cursorY: -1}
+
+	full := prev == nil || len(prev.lines) != len(next.lines)
+	if !full {
+		for y := range next.lines {
+			if len(prev.lines[y]) != len(next.lines[y]) {
+				full = true
+				break
+			}
+		}
+	}
+	if !full {
+		d.cursorX, d.cursorY = int(prev.cursorX), int(prev.cursorY)
+	}
+
+	// The host cursor visibility is unknown before a full redraw
+	visible := full || prev.CursorVisible()
+	for y, line := range next.lines {
+		changed := make([]bool, len(line))
+		for x := range line {
+			changed[x] = full || line[x] != prev.lines[y][x]
+		}
+		// Wide characters are redrawn together with their spacer
+		for x := range line {
+			if !changed[x] {
+				continue
+			}
+			if line[x].WideCharSpacer && x > 0 {
+				changed[x-1] = true
+			}
+			if line[x].WideChar && x+1 < len(line) {
+				changed[x+1] = true
+			}
+		}
+
+		for x := 0; x < len(line); {
+			if !changed[x] {
+				x++
+				continue
+			}
+
+			// Extend the run over short gaps of unchanged cells, which are
+			// cheaper to redraw than to skip
+			end := x + 1
+			for end < len(line) {
+				if changed[end] {
+					end++
+					continue
+				}
+				gap := end
+				for gap < len(line) && !changed[gap] && gap-end < diffGap {
+					gap++
+				}
+				if gap < len(line) && changed[gap] && gap-end < diffGap {
+					end = gap
+					continue
+				}
+				break
+			}
+
+			if visible {
+				// Hide the cursor while drawing to avoid flicker
+				buf.WriteString("\x1b[?25l")
+				visible = false
+			}
+			d.draw(line, y, x, end)
+			x = end
+		}
+	}
+
+	if d.pen != (sgrState{}) {
+		buf.WriteString("\x1b[0m")
+	}
+	d.moveTo(int(next.cursorX), int(next.cursorY))
+	if next.CursorVisible() && !visible {
+		buf.WriteString("\x1b[?25h")
+	} else if !next.CursorVisible() && visible {
+		buf.WriteString("\x1b[?25l")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// differ tracks the host cursor and rendition while RenderDiff writes
+type differ struct {
+	buf  *bytes.Buffer
+	opts DiffOptions
+	pen  sgrState
+
+	// cursorX and cursorY are the host cursor position in frame
+	// coordinates, or -1 if it is unknown
+	cursorX, cursorY int
+}
+
+// draw writes line[from:to] at row y
+func (d *differ) draw(line []Cell, y, from, to int) {
+	d.moveTo(from, y)
+
+	for x := from; x < to; x++ {
+		cell := line[x]
+		if cell.WideCharSpacer {
+			continue
+		}
+
+		next := sgrStateOf(cell)
+		next.fg = next.fg.downgrade(d.opts.Profile)
+		next.bg = next.bg.downgrade(d.opts.Profile)
+		d.buf.WriteString(d.pen.transition(next))
+		d.pen = next
+
+		if cell.Char == 0 {
+			d.buf.WriteByte(' ')
+		} else {
+			d.buf.WriteRune(cell.Char)
+		}
+
+		d.cursorX++
+		if cell.WideChar {
+			d.cursorX++
+		}
+	}
+
+	// Terminals disagree on where the cursor is after writing the last
+	// column, so the next movement is absolute
+	if d.cursorX >= len(line) {
+		d.cursorX, d.cursorY = -1, -1
+	}
+}
+
+// moveTo moves the host cursor to (x, y) with the shortest sequence
+func (d *differ) moveTo(x, y int) {
+	if d.cursorX == x && d.cursorY == y {
+		return
+	}
+
+	absolute := fmt.Sprintf("\x1b[%d;%dH", y+d.opts.OffsetY+1, x+d.opts.OffsetX+1)
+	best := absolute
+
+	if d.cursorX >= 0 {
+		var rel string
+		switch {
+		case y < d.cursorY:
+			rel = csiMove(d.cursorY-y, 'A')
+		case y > d.cursorY:
+			rel = csiMove(y-d.cursorY, 'B')
+		}
+
+		horizontal := ""
+		switch {
+		case x > d.cursorX:
+			horizontal = csiMove(x-d.cursorX, 'C')
+		case x < d.cursorX:
+			horizontal = csiMove(d.cursorX-x, 'D')
+		}
+		if cr := "\r" + csiMove(x+d.opts.OffsetX, 'C'); x < d.cursorX && len(cr) < len(horizontal) {
+			horizontal = cr
+		}
+
+		if len(rel+horizontal) < len(best) {
+			best = rel + horizontal
+		}
+	}
+
+	d.buf.WriteString(best)
+	d.cursorX, d.cursorY = x, y
+}
+
+// csiMove returns the cursor movement by n cells in direction dir
+func csiMove(n int, dir byte) string {
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return "\x1b[" + string(dir)
+	default:
+		return fmt.Sprintf("\x1b[%d%c", n, dir)
+	}
+}
+
+// downgrade maps c onto the colors available with profile
+func (c sgrColor) downgrade(profile ColorProfile) sgrColor {
+	if !c.set || profile == TrueColor {
+		return c
+	}
+
+	if c.index == IndexDirect {
+		if profile == ANSI16 {
+			return sgrColor{set: true, index: nearestColor(c.rgb, 0, 16)}
+		}
+		return sgrColor{set: true, index: nearestColor(c.rgb, 16, 256)}
+	}
+	if profile == ANSI16 && c.index >= 16 {
+		return sgrColor{set: true, index: nearestColor(xtermColor(c.index), 0, 16)}
+	}
+	return c
+}
+
+// nearestColor returns the index in [from, to) of the xterm palette color
+// closest to c
+func nearestColor(c RGB, from, to int) uint16 {
+	best, bestDist := from, -1
+	for i := from; i < to; i++ {
+		p := xtermColor(uint16(i))
+		dr, dg, db := int(c.R)-int(p.R), int(c.G)-int(p.G), int(c.B)-int(p.B)
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return uint16(best)
+}
+
+// xtermColor returns the RGB value of an indexed color: the palette colors
+// for 0-15, the 6x6x6 color cube for 16-231 and the gray ramp for 232-255
+func xtermColor(index uint16) RGB {
+	switch {
+	case index < 16:
+		return DefaultPalette().ANSI[index]
+	case index < 232:
+		levels := [6]uint8{0, 95, 135, 175, 215, 255}
+		i := index - 16
+		return RGB{R: levels[i/36], G: levels[i/6%6], B: levels[i%6]}
+	default:
+		gray := uint8(8 + 10*(index-232))
+		return RGB{R: gray, G: gray, B: gray}
+	}
+}
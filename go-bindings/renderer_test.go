@@ -0,0 +1,106 @@
+package alacritty
+
+import (
+	"bytes"
+	"testing"
+)
+
+func screenOf(cursorX uint32, visible bool, rows ...string) *Screen {
+	s := &Screen{cursorX: cursorX}
+	if visible {
+		s.modes = ModeShowCursor
+	}
+	for _, row := range rows {
+		var line []Cell
+		for _, c := range row {
+			line = append(line, plainCell(c))
+		}
+		s.lines = append(s.lines, line)
+	}
+	s.rows = uint32(len(s.lines))
+	if len(s.lines) > 0 {
+		s.cols = uint32(len(s.lines[0]))
+	}
+	return s
+}
+
+func TestRenderDiffUnchanged(t *testing.T) {
+	var buf bytes.Buffer
+	f := screenOf(3, true, "abc ", "def ")
+	if err := RenderDiff(&buf, f, f, DiffOptions{}); err != nil {
+		t.Fatalf("Failed to render diff: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output, got %q", buf.String())
+	}
+}
+
+func TestRenderDiffSingleCell(t *testing.T) {
+	var buf bytes.Buffer
+	prev := screenOf(3, true, "abc ", "def ")
+	next := screenOf(3, true, "aXc ", "def ")
+	if err := RenderDiff(&buf, prev, next, DiffOptions{}); err != nil {
+		t.Fatalf("Failed to render diff: %v", err)
+	}
+
+	expected := "\x1b[?25l\x1b[2DX\x1b[C\x1b[?25h"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestRenderDiffMergesGaps(t *testing.T) {
+	var buf bytes.Buffer
+	prev := screenOf(0, false, "abcdefghij")
+	next := screenOf(0, false, "Xbc??fghiY")
+	next.cursorY = 1
+	if err := RenderDiff(&buf, prev, next, DiffOptions{OffsetX: 2, OffsetY: 5}); err != nil {
+		t.Fatalf("Failed to render diff: %v", err)
+	}
+
+	// "Xbc??" is one run, "fghi" is too long a gap to redraw, and writing
+	// the last column makes the final cursor movement absolute
+	expected := "Xbc??\x1b[4CY\x1b[7;3H"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestRenderDiffFullRedraw(t *testing.T) {
+	var buf bytes.Buffer
+	next := screenOf(1, true, "ab", "cd")
+	next.lines[1][0].Bold = true
+	if err := RenderDiff(&buf, nil, next, DiffOptions{}); err != nil {
+		t.Fatalf("Failed to render diff: %v", err)
+	}
+
+	expected := "\x1b[?25l\x1b[1;1Hab\x1b[2;1H\x1b[1mc\x1b[22md\x1b[1;2H\x1b[?25h"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestColorDowngrade(t *testing.T) {
+	orange := sgrColor{set: true, index: IndexDirect, rgb: RGB{R: 255, G: 135, B: 0}}
+	tests := []struct {
+		name     string
+		color    sgrColor
+		profile  ColorProfile
+		expected sgrColor
+	}{
+		{"Truecolor kept", orange, TrueColor, orange},
+		{"Direct to 256", orange, ANSI256, sgrColor{set: true, index: 208}},
+		{"Direct to 16", sgrColor{set: true, index: IndexDirect, rgb: RGB{R: 250, G: 10, B: 10}}, ANSI16, sgrColor{set: true, index: 1}},
+		{"Cube to 16", sgrColor{set: true, index: 21}, ANSI16, sgrColor{set: true, index: 4}},
+		{"Basic kept", sgrColor{set: true, index: 3}, ANSI16, sgrColor{set: true, index: 3}},
+		{"Default kept", sgrColor{}, ANSI16, sgrColor{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.color.downgrade(tt.profile); got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package alacritty
+
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+// ReplayOptions controls the keyframes of a Replay
+type ReplayOptions struct {
+	// KeyframeBytes is the amount of output between keyframes, 64 KiB if
+	// zero. Smaller values make seeking faster and use more memory.
+	KeyframeBytes int
+}
+
+// Replay seeks through a recording. It keeps a terminal at the last
+// position it was seeked to and keyframes of the terminal state taken
+// periodically while loading, so that a seek replays at most the output
+// between two keyframes instead of the whole recording.
+//
+// Keyframes hold the state encoded by Terminal.MarshalBinary, without the
+// scrollback unless the recording resizes the terminal.
+type Replay struct {
+	Header CastHeader
+
+	events    []CastEvent
+	keyframes []keyframe
+	term      *Terminal
+	pos       int // number of events applied to term
+}
+
+// keyframe is the terminal state after the first pos events. The first
+// keyframe is the empty terminal the recording starts with and has no state.
+type keyframe struct {
+	pos        int
+	cols, rows uint32
+	state      []byte
+}
+
+// LoadReplay reads a recording and takes its keyframes
+func LoadReplay(r io.Reader, opts ReplayOptions) (*Replay, error) {
+	cast, err := NewCastReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []CastEvent
+	for {
+		event, err := cast.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return NewReplay(cast.Header, events, opts)
+}
+
+// NewReplay plays events once into a terminal of the size given by header
+// to take the keyframes. Events must be ordered by time.
+func NewReplay(header CastHeader, events []CastEvent, opts ReplayOptions) (*Replay, error) {
+	limit := opts.KeyframeBytes
+	if limit <= 0 {
+		limit = 64 * 1024
+	}
+
+	r := &Replay{
+		Header:    header,
+		events:    events,
+		keyframes: []keyframe{{cols: header.Width, rows: header.Height}},
+	}
+	if err := r.restore(r.keyframes[0]); err != nil {
+		return nil, err
+	}
+
+	// Lines pulled back from the history when the screen grows are part of
+	// the state once the recording resizes
+	scrollback := false
+	for _, event := range events {
+		if event.Type == CastResize {
+			scrollback = true
+			break
+		}
+	}
+
+	pending := 0
+	for r.pos < len(events) {
+		event := events[r.pos]
+		if err := event.apply(r.term); err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.pos++
+
+		if event.Type != CastOutput {
+			continue
+		}
+		pending += len(event.Data)
+		if pending < limit {
+			continue
+		}
+
+		state, err := r.term.marshal(scrollback)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.keyframes = append(r.keyframes, keyframe{pos: r.pos, state: state})
+		pending = 0
+	}
+
+	return r, nil
+}
+
+// Duration returns the time of the last event
+func (r *Replay) Duration() time.Duration {
+	if len(r.events) == 0 {
+		return 0
+	}
+	return r.events[len(r.events)-1].Time
+}
+
+// Seek returns the screen after every event up to and including time t.
+// It returns ErrClosed once the replay is closed.
+func (r *Replay) Seek(t time.Duration) (*Screen, error) {
+	if r.term == nil {
+		return nil, ErrClosed
+	}
+
+	target := sort.Search(len(r.events), func(i int) bool {
+		return r.events[i].Time > t
+	})
+	k := sort.Search(len(r.keyframes), func(i int) bool {
+		return r.keyframes[i].pos > target
+	}) - 1
+
+	// Restore the closest keyframe unless playing on from the current
+	// position is shorter
+	if target < r.pos || r.keyframes[k].pos > r.pos {
+		if err := r.restore(r.keyframes[k]); err != nil {
+			return nil, err
+		}
+	}
+	for ; r.pos < target; r.pos++ {
+		if err := r.events[r.pos].apply(r.term); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.term.Snapshot()
+}
+
+// Close releases the terminal of the replay
+func (r *Replay) Close() {
+	if r.term != nil {
+		r.term.Close()
+		r.term = nil
+	}
+}
+
+func (r *Replay) restore(k keyframe) error {
+	if k.state == nil {
+		term, err := New(k.cols, k.rows)
+		if err != nil {
+			return err
+		}
+		r.Close()
+		r.term = term
+	} else if err := r.term.UnmarshalBinary(k.state); err != nil {
+		return err
+	}
+
+	r.pos = k.pos
+	return nil
+}
@@ -0,0 +1,74 @@
+package alacritty
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplaySeek(t *testing.T) {
+	header := CastHeader{Version: 2, Width: 20, Height: 4}
+	var events []CastEvent
+	for i := 0; i < 40; i++ {
+		data := fmt.Sprintf("\x1b[3%dmline %d\x1b[0m\r\n", i%8, i)
+		if i%10 == 5 {
+			// Keyframes are taken in the middle of this sequence
+			events = append(events, CastEvent{Time: time.Duration(i) * time.Second, Type: CastOutput, Data: data + "\x1b[1"})
+			data = "mbold "
+		}
+		events = append(events, CastEvent{Time: time.Duration(i)*time.Second + time.Millisecond, Type: CastOutput, Data: data})
+	}
+	events = append(events, CastEvent{Time: 41 * time.Second, Type: CastResize, Data: "10x6"})
+
+	replay, err := NewReplay(header, events, ReplayOptions{KeyframeBytes: 50})
+	if err != nil {
+		t.Fatalf("Failed to create replay: %v", err)
+	}
+	defer replay.Close()
+
+	if len(replay.keyframes) < 10 {
+		t.Errorf("Expected keyframes every few events, got %d", len(replay.keyframes))
+	}
+	if replay.Duration() != 41*time.Second {
+		t.Errorf("Duration: expected 41s, got %v", replay.Duration())
+	}
+
+	// Seek out of order and compare with playing from the start
+	for _, at := range []time.Duration{17 * time.Second, 3 * time.Second, 3 * time.Second, 25500 * time.Millisecond, 0, 45 * time.Second, 35 * time.Second} {
+		got, err := replay.Seek(at)
+		if err != nil {
+			t.Fatalf("Seek(%v): %v", at, err)
+		}
+
+		term := NewTerminal(header.Width, header.Height)
+		if term == nil {
+			t.Fatal("Failed to create terminal")
+		}
+		for _, event := range events {
+			if event.Time <= at {
+				event.apply(term)
+			}
+		}
+		want, _ := term.Snapshot()
+		term.Close()
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Seek(%v): screen differs from playing from the start", at)
+		}
+	}
+}
+
+func TestReplaySeekClosed(t *testing.T) {
+	header := CastHeader{Version: 2, Width: 10, Height: 2}
+	replay, err := NewReplay(header, []CastEvent{{Type: CastOutput, Data: "hello"}}, ReplayOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create replay: %v", err)
+	}
+	replay.Close()
+
+	if _, err := replay.Seek(0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
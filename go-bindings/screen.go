@@ -0,0 +1,277 @@
+package alacritty
+
+import "strings"
+
+// Screen is a copy of the terminal contents taken by Terminal.Snapshot.
+// It is owned by Go and never changes, so it can be shared between
+// goroutines and read while the terminal keeps processing output.
+type Screen struct {
+	cols, rows       uint32
+	lines            [][]Cell
+	history          [][]Cell // oldest line first
+	cursorX, cursorY uint32
+	wrapPending      bool
+	// scrollTop and scrollBottom are the scrolling region as its first line
+	// and the line after its last, or zeros without one
+	scrollTop, scrollBottom uint32
+	// primary is the primary screen behind the alternate screen, or nil
+	// while the primary screen is shown
+	primary *primaryScreen
+	modes   Mode
+	pen     Cell
+	title   string
+	palette Palette
+	// marks are the OSC 133 marks and dir the working directory, for the
+	// shell integration queries
+	marks []mark
+	dir   string
+}
+
+// primaryScreen is the primary screen behind the alternate screen, which
+// is shown again when the application leaves the alternate screen
+type primaryScreen struct {
+	lines            [][]Cell
+	cursorX, cursorY uint32
+	wrapPending      bool
+	pen              Cell
+}
+
+// Snapshot copies the screen, cursor, modes, pen, title, palette and the
+// shell integration marks and working directory in one step, so the result
+// is consistent even while another goroutine writes
+func (t *Terminal) Snapshot() (*Screen, error) {
+	return t.snapshot(false)
+}
+
+// SnapshotWithScrollback is Snapshot including the scrollback history
+func (t *Terminal) SnapshotWithScrollback() (*Screen, error) {
+	return t.snapshot(true)
+}
+
+func (t *Terminal) snapshot(scrollback bool) (*Screen, error) {
+	t.mu.RLock()
+	s, err := t.capture(scrollback)
+	t.mu.RUnlock()
+	if err != nil || s.modes&ModeAltScreen == 0 {
+		return s, err
+	}
+
+	// Reading the primary screen behind the alternate screen swaps the
+	// screens in the library, which readers must not see, so the snapshot
+	// is taken again under the exclusive lock
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s, err = t.capture(scrollback); err != nil || s.modes&ModeAltScreen == 0 {
+		return s, err
+	}
+	if s.primary, err = t.primaryScreen(s.rows); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// capture copies the screen shown for snapshot
+func (t *Terminal) capture(scrollback bool) (*Screen, error) {
+	cols, rows, err := t.size()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Screen{cols: cols, rows: rows}
+	if s.lines, err = t.lines(rows); err != nil {
+		return nil, err
+	}
+
+	if scrollback {
+		history, err := t.historySize()
+		if err != nil {
+			return nil, err
+		}
+		s.history = make([][]Cell, history)
+		for n := uint32(0); n < history; n++ {
+			if s.history[n], err = t.historyLine(n); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if s.cursorX, s.cursorY, err = t.cursor(); err != nil {
+		return nil, err
+	}
+	if s.wrapPending, err = t.wrapPending(); err != nil {
+		return nil, err
+	}
+	if s.scrollTop, s.scrollBottom, err = t.scrollRegion(); err != nil {
+		return nil, err
+	}
+	if s.scrollTop == 0 && s.scrollBottom == rows {
+		s.scrollBottom = 0
+	}
+	if s.modes, err = t.modes(); err != nil {
+		return nil, err
+	}
+	if s.pen, err = t.pen(); err != nil {
+		return nil, err
+	}
+	if s.title, err = t.title(); err != nil {
+		return nil, err
+	}
+	colors, err := t.colors()
+	if err != nil {
+		return nil, err
+	}
+	s.palette = colors.Palette
+	if s.marks, err = t.marks(); err != nil {
+		return nil, err
+	}
+	if s.dir, err = t.workingDirectory(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// primaryScreen copies the primary screen behind the alternate screen, for
+// callers holding the exclusive lock
+func (t *Terminal) primaryScreen(rows uint32) (*primaryScreen, error) {
+	if err := t.showPrimary(true); err != nil {
+		return nil, err
+	}
+	p, err := t.capturePrimary(rows)
+	if endErr := t.showPrimary(false); err == nil {
+		err = endErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// capturePrimary copies the screen shown by showPrimary
+func (t *Terminal) capturePrimary(rows uint32) (*primaryScreen, error) {
+	p := &primaryScreen{}
+	var err error
+	if p.lines, err = t.lines(rows); err != nil {
+		return nil, err
+	}
+	if p.cursorX, p.cursorY, err = t.cursor(); err != nil {
+		return nil, err
+	}
+	if p.wrapPending, err = t.wrapPending(); err != nil {
+		return nil, err
+	}
+	if p.pen, err = t.pen(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// lines copies the lines of the screen shown
+func (t *Terminal) lines(rows uint32) ([][]Cell, error) {
+	lines := make([][]Cell, rows)
+	for y := range lines {
+		var err error
+		if lines[y], err = t.line(uint32(y)); err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+// Size returns the size of the screen
+func (s *Screen) Size() (cols, rows uint32) {
+	return s.cols, s.rows
+}
+
+// Cell returns the cell at the specified position, or the zero Cell
+// outside the screen
+func (s *Screen) Cell(x, y uint32) Cell {
+	if y >= s.rows || x >= uint32(len(s.lines[y])) {
+		return Cell{}
+	}
+	return s.lines[y][x]
+}
+
+// Line returns a copy of the cells of a line, or nil outside the screen
+func (s *Screen) Line(y uint32) []Cell {
+	if y >= s.rows {
+		return nil
+	}
+	return append([]Cell(nil), s.lines[y]...)
+}
+
+// HistorySize returns the number of scrollback lines in the snapshot, which
+// is 0 unless it was taken with SnapshotWithScrollback
+func (s *Screen) HistorySize() uint32 {
+	return uint32(len(s.history))
+}
+
+// HistoryLine returns a copy of the cells of a scrollback line, where line
+// 0 is the oldest, or nil outside the history
+func (s *Screen) HistoryLine(n uint32) []Cell {
+	if n >= uint32(len(s.history)) {
+		return nil
+	}
+	return append([]Cell(nil), s.history[n]...)
+}
+
+// Cursor returns the cursor position
+func (s *Screen) Cursor() (x, y uint32) {
+	return s.cursorX, s.cursorY
+}
+
+// CursorVisible reports whether the cursor is shown
+func (s *Screen) CursorVisible() bool {
+	return s.modes&ModeShowCursor != 0
+}
+
+// Modes returns the terminal modes
+func (s *Screen) Modes() Mode {
+	return s.modes
+}
+
+// Pen returns the colors and attributes applied to newly written text
+func (s *Screen) Pen() Cell {
+	return s.pen
+}
+
+// Title returns the window title
+func (s *Screen) Title() string {
+	return s.title
+}
+
+// Palette returns the palette in use, including the changes applications
+// made with OSC 4, 10 and 11
+func (s *Screen) Palette() Palette {
+	return s.palette
+}
+
+// String returns the text of the screen, one line per row
+func (s *Screen) String() string {
+	var b strings.Builder
+	for y, line := range s.lines {
+		for _, cell := range line {
+			if cell.Char == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(cell.Char)
+			}
+		}
+		if y < len(s.lines)-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// allLines returns the screen lines, preceded by the history if scrollback
+// is set
+func (s *Screen) allLines(scrollback bool) [][]Cell {
+	if !scrollback || len(s.history) == 0 {
+		return s.lines
+	}
+	lines := make([][]Cell, 0, len(s.history)+len(s.lines))
+	lines = append(lines, s.history...)
+	return append(lines, s.lines...)
+}
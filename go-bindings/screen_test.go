@@ -0,0 +1,56 @@
+package alacritty
+
+import "testing"
+
+func TestSnapshotIsImmutable(t *testing.T) {
+	term := NewTerminal(10, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	term.Write([]byte("before"))
+	s, err := term.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	term.Write([]byte("\r\nafter"))
+	term.Resize(20, 4)
+	s.Line(0)[0].Char = 'X'
+
+	if cols, rows := s.Size(); cols != 10 || rows != 2 {
+		t.Errorf("Expected a 10x2 snapshot, got %dx%d", cols, rows)
+	}
+	if got := s.String(); got != "before    \n          " {
+		t.Errorf("Snapshot changed with the terminal: %q", got)
+	}
+	if x, y := s.Cursor(); x != 6 || y != 0 {
+		t.Errorf("Expected the cursor at (6, 0), got (%d, %d)", x, y)
+	}
+	if cell := s.Cell(0, 5); cell != (Cell{}) {
+		t.Errorf("Expected the zero cell outside the screen, got %+v", cell)
+	}
+}
+
+func TestSnapshotTitle(t *testing.T) {
+	term := NewTerminal(10, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	term.Write([]byte("\x1b]2;build: ok\x07\x1b[?25l"))
+	s, err := term.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	term.Write([]byte("\x1b]2;other\x07"))
+
+	if s.Title() != "build: ok" {
+		t.Errorf("Expected title %q, got %q", "build: ok", s.Title())
+	}
+	if s.CursorVisible() {
+		t.Error("Expected the cursor to be hidden")
+	}
+}
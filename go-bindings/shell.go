@@ -0,0 +1,340 @@
+package alacritty
+
+/*
+#include "alacritty_ffi.h"
+*/
+import "C"
+import (
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Point is a position in the terminal. Line 0 is the top of the screen and
+// negative lines are the scrollback history, -1 being the newest.
+type Point struct {
+	Line   int
+	Column uint32
+}
+
+// Region is the text from Start up to, but not including, End
+type Region struct {
+	Start, End Point
+}
+
+// Command is a command run by a shell that reports it with the OSC 133
+// marks of FinalTerm shell integration
+type Command struct {
+	// Prompt spans the prompt, from mark A to mark B
+	Prompt Region
+	// Input spans the command line, from mark B to mark C
+	Input Region
+	// Text is the command line without surrounding whitespace
+	Text string
+	// Output spans the output, from mark C to mark D or to the cursor
+	// while the command runs
+	Output Region
+	// ExitCode is the exit code sent with mark D, or 0 if there was none
+	ExitCode int
+	// Finished reports whether mark D was received
+	Finished bool
+}
+
+// Kinds of marks, sent as OSC 133 A to D
+const (
+	markPromptStart  = C.MARK_PROMPT_START
+	markCommandStart = C.MARK_COMMAND_START
+	markOutputStart  = C.MARK_OUTPUT_START
+	markCommandEnd   = C.MARK_COMMAND_END
+)
+
+// mark is an OSC 133 mark as reported by the library
+type mark struct {
+	Point
+	kind     uint8
+	exitCode int
+}
+
+// Commands lists the commands whose prompt is still on the screen or in
+// the scrollback, oldest first. Marks follow their lines as they scroll
+// and keep their place in the text when a resize rewraps lines. The
+// alternate screen has no commands.
+func (t *Terminal) Commands() ([]Command, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	marks, err := t.marks()
+	if err != nil {
+		return nil, err
+	}
+	cursorX, cursorY, err := t.cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	return listCommands(marks, Point{Line: int(cursorY), Column: cursorX}, t.regionText)
+}
+
+// Commands lists the commands whose prompt was on the screen or in the
+// scrollback when the snapshot was taken, like Terminal.Commands. The text
+// of command lines in the scrollback is empty unless the snapshot was
+// taken with SnapshotWithScrollback.
+func (s *Screen) Commands() []Command {
+	cursor := Point{Line: int(s.cursorY), Column: s.cursorX}
+	commands, _ := listCommands(s.marks, cursor, func(r Region) (string, error) {
+		return s.RegionText(r), nil
+	})
+	return commands
+}
+
+// listCommands builds the commands of marks and reads their command lines
+// with regionText
+func listCommands(marks []mark, cursor Point, regionText func(Region) (string, error)) ([]Command, error) {
+	commands := buildCommands(marks, cursor)
+	for i := range commands {
+		text, err := regionText(commands[i].Input)
+		if err != nil {
+			return nil, err
+		}
+		commands[i].Text = strings.TrimSpace(text)
+	}
+	return commands, nil
+}
+
+// buildCommands pairs up the marks of each command. The output of a
+// command that has not finished ends at cursor.
+func buildCommands(marks []mark, cursor Point) []Command {
+	var commands []Command
+	var cmd *Command
+	var phase uint8
+	finish := func(end Point) {
+		// A prompt without a command line only ran an empty command
+		if cmd != nil && phase >= markOutputStart {
+			if !cmd.Finished {
+				cmd.Output.End = end
+			}
+			commands = append(commands, *cmd)
+		}
+		cmd = nil
+	}
+
+	for _, m := range marks {
+		if m.kind == markPromptStart {
+			finish(m.Point)
+			cmd, phase = &Command{Prompt: Region{Start: m.Point, End: m.Point}}, m.kind
+			continue
+		}
+		// Marks of a command whose prompt left the history are skipped
+		if cmd == nil || m.kind <= phase {
+			continue
+		}
+
+		switch m.kind {
+		case markCommandStart:
+			cmd.Prompt.End = m.Point
+			cmd.Input = Region{Start: m.Point, End: m.Point}
+		case markOutputStart:
+			if phase < markCommandStart {
+				cmd.Prompt.End, cmd.Input.Start = m.Point, m.Point
+			}
+			cmd.Input.End = m.Point
+			cmd.Output = Region{Start: m.Point, End: m.Point}
+		case markCommandEnd:
+			if phase < markOutputStart {
+				// The command did not run
+				cmd = nil
+				continue
+			}
+			cmd.Output.End = m.Point
+			cmd.ExitCode = m.exitCode
+			cmd.Finished = true
+		}
+		phase = m.kind
+	}
+	finish(cursor)
+
+	return commands
+}
+
+// marks returns the OSC 133 marks, oldest first. The caller holds the lock.
+func (t *Terminal) marks() ([]mark, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return nil, ErrClosed
+	}
+
+	result := C.terminal_get_marks(t.ptr, nil, 0)
+	if result <= 0 {
+		if result < 0 {
+			return nil, lastError("Commands", result)
+		}
+		return nil, nil
+	}
+
+	cMarks := make([]C.CMark, result)
+	result = C.terminal_get_marks(t.ptr, &cMarks[0], C.size_t(len(cMarks)))
+	if result < 0 {
+		return nil, lastError("Commands", result)
+	}
+
+	marks := make([]mark, len(cMarks))
+	for i, m := range cMarks {
+		marks[i] = mark{
+			Point:    Point{Line: int(m.line), Column: uint32(m.column)},
+			kind:     uint8(m.kind),
+			exitCode: int(m.exit_code),
+		}
+	}
+	return marks, nil
+}
+
+// RegionText returns the text of a region, with a newline after each line
+// that does not wrap into the next one and without trailing blanks
+func (t *Terminal) RegionText(r Region) (string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.regionText(r)
+}
+
+func (t *Terminal) regionText(r Region) (string, error) {
+	if t.ptr == nil {
+		return "", ErrClosed
+	}
+
+	history, err := t.historySize()
+	if err != nil {
+		return "", err
+	}
+
+	return regionText(r, func(y int) ([]Cell, error) {
+		if y >= 0 {
+			return t.line(uint32(y))
+		}
+		return t.historyLine(uint32(int(history) + y))
+	})
+}
+
+// RegionText returns the text of a region like Terminal.RegionText. Lines
+// of the scrollback are empty unless the snapshot was taken with
+// SnapshotWithScrollback.
+func (s *Screen) RegionText(r Region) string {
+	text, _ := regionText(r, func(y int) ([]Cell, error) {
+		switch {
+		case y >= 0 && y < len(s.lines):
+			return s.lines[y], nil
+		case y < 0 && -y <= len(s.history):
+			return s.history[len(s.history)+y], nil
+		}
+		return nil, nil
+	})
+	return text
+}
+
+// regionText joins the text of the lines of r, read with readLine
+func regionText(r Region, readLine func(y int) ([]Cell, error)) (string, error) {
+	var b strings.Builder
+	for y := r.Start.Line; y <= r.End.Line; y++ {
+		from, to := uint32(0), ^uint32(0)
+		if y == r.Start.Line {
+			from = r.Start.Column
+		}
+		if y == r.End.Line {
+			if r.End.Column == 0 && y > r.Start.Line {
+				break
+			}
+			to = r.End.Column
+		}
+
+		line, err := readLine(y)
+		if err != nil {
+			return "", err
+		}
+
+		var text strings.Builder
+		for x := from; x < to && x < uint32(len(line)); x++ {
+			switch cell := line[x]; {
+			case cell.WideCharSpacer:
+			case cell.Char == 0:
+				text.WriteByte(' ')
+			default:
+				text.WriteRune(cell.Char)
+			}
+		}
+		// A line that wraps continues on the next one, blanks included
+		wraps := len(line) > 0 && line[len(line)-1].Wrapline && to >= uint32(len(line))
+		if wraps || y == r.End.Line {
+			b.WriteString(text.String())
+		} else {
+			b.WriteString(strings.TrimRight(text.String(), " "))
+		}
+		if y < r.End.Line && !wraps {
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.String(), nil
+}
+
+// WorkingDirectory returns the directory the shell last reported with
+// OSC 7, or "" if it reported none or one on another host
+func (t *Terminal) WorkingDirectory() (string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.workingDirectory()
+}
+
+// WorkingDirectory returns the directory the shell last reported with
+// OSC 7 when the snapshot was taken, like Terminal.WorkingDirectory
+func (s *Screen) WorkingDirectory() string {
+	return s.dir
+}
+
+func (t *Terminal) workingDirectory() (string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return "", ErrClosed
+	}
+
+	result := C.terminal_get_working_directory(t.ptr, nil, 0)
+	if result < 0 {
+		return "", lastError("WorkingDirectory", result)
+	}
+	if result == 0 {
+		return "", nil
+	}
+
+	buf := make([]byte, result)
+	result = C.terminal_get_working_directory(t.ptr, (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
+	if result < 0 {
+		return "", lastError("WorkingDirectory", result)
+	}
+
+	hostname, _ := localHostname()
+	return localDirectory(string(buf), hostname), nil
+}
+
+// localHostname is the name of this host, against which reported
+// directories are checked
+var localHostname = sync.OnceValues(os.Hostname)
+
+// localDirectory returns the decoded path of an OSC 7 URL, or "" if it is
+// not a file URL on this host
+func localDirectory(rawURL, hostname string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return ""
+	}
+	if u.Host != "" && !strings.EqualFold(u.Hostname(), "localhost") && !strings.EqualFold(u.Hostname(), hostname) {
+		return ""
+	}
+	return u.Path
+}
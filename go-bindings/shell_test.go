@@ -0,0 +1,220 @@
+package alacritty
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildCommands(t *testing.T) {
+	at := func(line int, column uint32) Point { return Point{Line: line, Column: column} }
+	marks := []mark{
+		// Output of a command whose prompt left the history
+		{Point: at(-9, 0), kind: markCommandEnd},
+		{Point: at(-8, 0), kind: markPromptStart},
+		{Point: at(-8, 2), kind: markCommandStart},
+		{Point: at(-7, 0), kind: markOutputStart},
+		{Point: at(-3, 0), kind: markCommandEnd, exitCode: 2},
+		// Empty command line
+		{Point: at(-3, 0), kind: markPromptStart},
+		{Point: at(-3, 2), kind: markCommandStart},
+		{Point: at(-2, 0), kind: markCommandEnd},
+		{Point: at(-2, 0), kind: markPromptStart},
+		{Point: at(-2, 2), kind: markCommandStart},
+		{Point: at(-1, 0), kind: markOutputStart},
+	}
+
+	expected := []Command{
+		{
+			Prompt:   Region{Start: at(-8, 0), End: at(-8, 2)},
+			Input:    Region{Start: at(-8, 2), End: at(-7, 0)},
+			Output:   Region{Start: at(-7, 0), End: at(-3, 0)},
+			ExitCode: 2,
+			Finished: true,
+		},
+		{
+			Prompt: Region{Start: at(-2, 0), End: at(-2, 2)},
+			Input:  Region{Start: at(-2, 2), End: at(-1, 0)},
+			Output: Region{Start: at(-1, 0), End: at(3, 4)},
+		},
+	}
+	if commands := buildCommands(marks, at(3, 4)); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, commands)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	term := NewTerminal(20, 4)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	for _, s := range []string{
+		"\x1b]133;A\x07$ \x1b]133;B\x07ls\r\n\x1b]133;C\x07",
+		"a.go\r\nb.go\r\n\x1b]133;D;0\x07",
+		"\x1b]133;A\x1b\\$ \x1b]133;B\x1b\\false\r\n\x1b]1",
+		"33;C\x1b\\\x1b]133;D;1\x1b\\",
+		"\x1b]133;A\x07$ ",
+	} {
+		term.Write([]byte(s))
+	}
+
+	commands, err := term.Commands()
+	if err != nil {
+		t.Fatalf("Commands: %v", err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("Expected 2 commands, got %+v", commands)
+	}
+
+	// The first prompt scrolled into the history
+	ls, fail := commands[0], commands[1]
+	if ls.Text != "ls" || !ls.Finished || ls.ExitCode != 0 {
+		t.Errorf("Unexpected first command %+v", ls)
+	}
+	if ls.Prompt.Start != (Point{Line: -1, Column: 0}) {
+		t.Errorf("Expected the first prompt on line -1, got %+v", ls.Prompt.Start)
+	}
+	output, err := term.RegionText(ls.Output)
+	if err != nil {
+		t.Fatalf("RegionText: %v", err)
+	}
+	if output != "a.go\nb.go\n" {
+		t.Errorf("Expected the output of ls, got %q", output)
+	}
+	if fail.Text != "false" || !fail.Finished || fail.ExitCode != 1 {
+		t.Errorf("Unexpected second command %+v", fail)
+	}
+}
+
+func TestScreenShellIntegration(t *testing.T) {
+	term := NewTerminal(20, 4)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	term.Write([]byte("\x1b]7;file://localhost/tmp\x07" +
+		"\x1b]133;A\x07$ \x1b]133;B\x07ls\r\n\x1b]133;C\x07a.go\r\nb.go\r\n\x1b]133;D;0\x07" +
+		"\x1b]133;A\x07$ \x1b]133;B\x07true\r\n\x1b]133;C\x07\x1b]133;D;0\x07\x1b]133;A\x07$ "))
+
+	want, err := term.Commands()
+	if err != nil {
+		t.Fatalf("Commands: %v", err)
+	}
+	wantOutput, err := term.RegionText(want[0].Output)
+	if err != nil {
+		t.Fatalf("RegionText: %v", err)
+	}
+
+	s, err := term.SnapshotWithScrollback()
+	if err != nil {
+		t.Fatalf("SnapshotWithScrollback: %v", err)
+	}
+	screenOnly, err := term.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	// The snapshots keep answering after the terminal moves on
+	term.Write([]byte("\x1b]7;file://localhost/usr\x07\x1b[2J"))
+
+	if got := s.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected commands %+v, got %+v", want, got)
+	}
+	if got := s.RegionText(want[0].Output); got != wantOutput {
+		t.Errorf("Expected output %q, got %q", wantOutput, got)
+	}
+	if dir := s.WorkingDirectory(); dir != "/tmp" {
+		t.Errorf("Expected /tmp, got %q", dir)
+	}
+
+	// Without the scrollback, the command line in it is unknown
+	if commands := screenOnly.Commands(); len(commands) != 2 || commands[0].Text != "" || commands[1].Text != "true" {
+		t.Errorf("Expected the commands without the text in the scrollback, got %+v", commands)
+	}
+}
+
+func TestCommandsAfterRewrap(t *testing.T) {
+	term := NewTerminal(10, 8)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	// The command line and its output wrap
+	term.Write([]byte("\x1b]133;A\x07$ \x1b]133;B\x07echo 0123456789ab\r\n\x1b]133;C\x07"))
+	term.Write([]byte("0123456789ab\r\n\x1b]133;D;0\x07\x1b]133;A\x07$ "))
+
+	for _, size := range [][2]uint32{{20, 8}, {7, 8}, {10, 4}} {
+		if err := term.Resize(size[0], size[1]); err != nil {
+			t.Fatalf("Resize: %v", err)
+		}
+		commands, err := term.Commands()
+		if err != nil {
+			t.Fatalf("Commands: %v", err)
+		}
+		if len(commands) != 1 || commands[0].Text != "echo 0123456789ab" {
+			t.Fatalf("%dx%d: expected the echo command, got %+v", size[0], size[1], commands)
+		}
+		output, err := term.RegionText(commands[0].Output)
+		if err != nil {
+			t.Fatalf("RegionText: %v", err)
+		}
+		if output != "0123456789ab\n" {
+			t.Errorf("%dx%d: expected the output of echo, got %q", size[0], size[1], output)
+		}
+	}
+}
+
+func TestLocalDirectory(t *testing.T) {
+	tests := []struct {
+		url, expected string
+	}{
+		{"file:///home/me/src", "/home/me/src"},
+		{"file://localhost/tmp", "/tmp"},
+		{"file://box/home/me/My%20Files", "/home/me/My Files"},
+		{"file://BOX/srv", "/srv"},
+		{"file://other/home/me", ""},
+		{"https://box/home/me", ""},
+		{"file://box", ""},
+		{"not a url\x7f", ""},
+	}
+
+	for _, test := range tests {
+		if dir := localDirectory(test.url, "box"); dir != test.expected {
+			t.Errorf("localDirectory(%q) = %q, expected %q", test.url, dir, test.expected)
+		}
+	}
+}
+
+func TestWorkingDirectory(t *testing.T) {
+	var changes []string
+	term, err := New(20, 4, WithWorkingDirectoryFunc(func(dir string) {
+		changes = append(changes, dir)
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+
+	for _, s := range []string{
+		"\x1b]7;file://localhost/tmp/a%20b\x07$ ",
+		"\x1b]7;file://localhost/tmp/a%20b\x1b\\$ ",
+		"\x1b]7;file://localhost/u",
+		"sr\x07",
+	} {
+		term.Write([]byte(s))
+	}
+
+	dir, err := term.WorkingDirectory()
+	if err != nil {
+		t.Fatalf("WorkingDirectory: %v", err)
+	}
+	if dir != "/usr" {
+		t.Errorf("Expected /usr, got %q", dir)
+	}
+	expected := []string{"/tmp/a b", "/usr"}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %q, got %q", expected, changes)
+	}
+}
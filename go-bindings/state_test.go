@@ -0,0 +1,276 @@
+package alacritty
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalBinaryRoundTrip(t *testing.T) {
+	term := NewTerminal(20, 5)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	setup := "line 1\r\nline 2\r\nline 3\r\nline 4\r\nline 5\r\nline 6\r\n" +
+		"\x1b]2;saved title\x07\x1b[22;0t\x1b]2;current title\x07" + // title stack
+		"\x1b[3g\x1b[1;6H\x1bH\x1b[1;13H\x1bH" + // tab stops at columns 6 and 13
+		"\x1b)0\x0e" + // line drawing in G1, shifted in
+		"\x1b]4;1;rgb:12/34/56\x07" + // palette
+		"\x1b[2;4r" + // scrolling region
+		"\x1b[3;3H\x1b[1;31m\x1b7\x1b[1;1H\x1b[0;4;44m" + // saved cursor and pen
+		"\x1b[?2004h\x1b[?1049hon alt\x1b[" // alternate screen, partial sequence
+	term.Write([]byte(setup))
+
+	data, err := term.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	restored := NewTerminal(1, 1)
+	if restored == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer restored.Close()
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	// Both terminals must react the same to everything that depends on the
+	// state that is not visible on the screen
+	continuation := "2mq\tx\tx\x0f" + // finish the sequence, tabs, shift out
+		"\x1b8saved" + // restore the cursor and pen
+		"\x1b[4;1H\n\n\n" + // scroll inside the region
+		"\x1b[?1049l\x1b[23;0t" + // back to the primary screen, pop the title
+		"\x1b[?2004l"
+	term.Write([]byte(continuation))
+	restored.Write([]byte(continuation))
+
+	want, _ := term.Snapshot()
+	got, _ := restored.Snapshot()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Screen differs after restoring:\nexpected %q\ngot      %q", term.String(), restored.String())
+	}
+
+	for n := uint32(0); ; n++ {
+		wantLine, wantErr := term.GetHistoryLine(n)
+		gotLine, gotErr := restored.GetHistoryLine(n)
+		if (wantErr == nil) != (gotErr == nil) {
+			t.Fatalf("History size differs at line %d", n)
+		}
+		if wantErr != nil {
+			break
+		}
+		if !reflect.DeepEqual(gotLine, wantLine) {
+			t.Errorf("History line %d differs", n)
+		}
+	}
+
+	wantModes, _ := term.Modes()
+	gotModes, _ := restored.Modes()
+	if wantModes != gotModes {
+		t.Errorf("Modes: expected %b, got %b", wantModes, gotModes)
+	}
+}
+
+func TestMarshalBinarySynchronizedUpdate(t *testing.T) {
+	term := NewTerminal(13, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	term.Write([]byte("before\x1b[?2026h\r\nheld back"))
+	data, err := term.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	restored := NewTerminal(1, 1)
+	if restored == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer restored.Close()
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	// Saving leaves the update held back, and so does restoring
+	for _, tt := range []*Terminal{term, restored} {
+		if screen := tt.String(); screen != "before       \n             " {
+			t.Errorf("Expected the update to be held back, got %q", screen)
+		}
+		tt.Write([]byte(" too\x1b[?2026l"))
+		if screen := tt.String(); screen != "before       \nheld back too" {
+			t.Errorf("Expected the update once it ends, got %q", screen)
+		}
+	}
+}
+
+func TestUnmarshalBinaryKeepsOptions(t *testing.T) {
+	term := NewTerminal(10, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+	term.Write([]byte("1\r\n2\r\n3\r\n4\r\n5\r\n6\r\n7"))
+
+	data, err := term.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	restored, err := New(1, 1, WithScrollback(3))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer restored.Close()
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	// The restored terminal keeps its own scrollback size
+	if size, err := restored.HistorySize(); err != nil || size != 3 {
+		t.Errorf("Expected 3 history lines, got %d (%v)", size, err)
+	}
+	if got := restored.String(); got != "6         \n7         " {
+		t.Errorf("Expected the screen to be restored, got %q", got)
+	}
+
+	var zero Terminal
+	if err := zero.UnmarshalBinary(data); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed for a zero Terminal, got %v", err)
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	term := NewTerminal(10, 2)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+	term.Write([]byte("kept"))
+
+	if err := term.UnmarshalBinary([]byte("not a terminal state")); err == nil {
+		t.Error("Expected an error for invalid state")
+	}
+
+	// State of an older format version, from before the working directory
+	data, err := term.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	data[3] = 2
+	if err := term.UnmarshalBinary(data); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for an older format, got %v", err)
+	}
+	if got := term.String(); got != "kept      \n          " {
+		t.Errorf("Expected the terminal to be unchanged, got %q", got)
+	}
+}
+
+func TestCheckpointRollback(t *testing.T) {
+	term := NewTerminal(20, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	term.Write([]byte("$ make\r\n"))
+	before := term.String()
+
+	cp, err := term.Checkpoint()
+	if err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	// A checkpoint without changes since shares the state of cp
+	same, _ := term.Checkpoint()
+
+	term.Write([]byte("\x1b[31mpreview output\r\nmore\r\nlines\x1b[?2004h"))
+	term.Resize(30, 5)
+	if err := term.Rollback(cp); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if got := term.String(); got != before {
+		t.Errorf("Screen after rollback:\nexpected %q\ngot      %q", before, got)
+	}
+	if modes, _ := term.Modes(); modes&ModeBracketedPaste != 0 {
+		t.Error("Expected bracketed paste to be rolled back")
+	}
+
+	// The checkpoint stays valid, and the pen is rolled back as well
+	term.Write([]byte("second"))
+	if err := term.Rollback(same); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	term.Write([]byte("ok"))
+	cell, _ := term.GetCell(0, 1)
+	if cell.Char != 'o' || cell.FgIndex != IndexForeground {
+		t.Errorf("Expected 'o' in the default color after rollback, got %+v", cell)
+	}
+
+	if err := term.ReleaseCheckpoint(cp); err != nil {
+		t.Errorf("ReleaseCheckpoint: %v", err)
+	}
+	if err := term.Rollback(cp); err == nil {
+		t.Error("Expected an error rolling back to a released checkpoint")
+	}
+}
+
+// allText returns the scrollback and the screen of term
+func allText(t *testing.T, term *Terminal) string {
+	t.Helper()
+	history, err := term.HistorySize()
+	if err != nil {
+		t.Fatalf("HistorySize: %v", err)
+	}
+	_, rows, _ := term.GetSize()
+	text, err := term.RegionText(Region{
+		Start: Point{Line: -int(history)},
+		End:   Point{Line: int(rows) - 1, Column: ^uint32(0)},
+	})
+	if err != nil {
+		t.Fatalf("RegionText: %v", err)
+	}
+	return text
+}
+
+func TestCheckpointRollbackScrollback(t *testing.T) {
+	term, err := New(10, 2, WithScrollback(3))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer term.Close()
+
+	term.Write([]byte("1\r\n2\r\n3\r\n4"))
+	before := allText(t, term)
+	cp, err := term.Checkpoint()
+	if err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+
+	// The lines of the checkpoint leave the scrollback
+	term.Write([]byte("\r\n5\r\n6\r\n7\r\n8\r\n9"))
+	if err := term.Rollback(cp); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if got := allText(t, term); got != before {
+		t.Errorf("Text after rollback:\nexpected %q\ngot      %q", before, got)
+	}
+
+	// Rolling back again after fewer lines moves the shared lines back
+	term.Write([]byte("\r\n5"))
+	if err := term.Rollback(cp); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if got := allText(t, term); got != before {
+		t.Errorf("Text after second rollback:\nexpected %q\ngot      %q", before, got)
+	}
+
+	// The scrollback keeps its configured size
+	term.Write([]byte("\r\n5\r\n6\r\n7\r\n8\r\n9"))
+	if history, _ := term.HistorySize(); history != 3 {
+		t.Errorf("Expected 3 lines of scrollback, got %d", history)
+	}
+}
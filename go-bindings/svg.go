@@ -0,0 +1,194 @@
+package alacritty
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SVGOptions controls the SVG export
+type SVGOptions struct {
+	// FontSize is the font size in pixels, 14 if zero
+	FontSize float64
+	// FontFamily is the CSS font family of the text, "monospace" if empty
+	FontFamily string
+	// Frame draws a window frame with a title bar around the screen
+	Frame bool
+	// Title is shown in the title bar of the frame
+	Title string
+	// Colors controls how bold, dim, inverse and hidden text is colored
+	Colors ColorOptions
+}
+
+const (
+	svgCellWidth   = 0.6 // cell width relative to the font size
+	svgLineHeight  = 1.2 // line height relative to the font size
+	svgPadding     = 12.0
+	svgTitleHeight = 28.0
+)
+
+// SVG returns the screen as a standalone SVG document with a background
+// rectangle and a text element per run of cells sharing a style. The output
+// only depends on the screen contents, so it can be committed and diffed.
+func (t *Terminal) SVG(opts SVGOptions) (string, error) {
+	s, err := t.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	return s.SVG(opts), nil
+}
+
+// SVG is Terminal.SVG for a snapshot
+func (s *Screen) SVG(opts SVGOptions) string {
+	return renderSVG(s.lines, s.palette, opts)
+}
+
+// svgStyle is the resolved look of a cell in the SVG output
+type svgStyle struct {
+	fg, bg                             RGB
+	bold, italic, underline, strikeout bool
+}
+
+func renderSVG(lines [][]Cell, palette Palette, opts SVGOptions) string {
+	fontSize := opts.FontSize
+	if fontSize <= 0 {
+		fontSize = 14
+	}
+	family := opts.FontFamily
+	if family == "" {
+		family = "monospace"
+	}
+	colors := opts.Colors
+	if colors.Palette == nil {
+		colors.Palette = &palette
+	}
+	cw, lh := fontSize*svgCellWidth, fontSize*svgLineHeight
+
+	cols := 0
+	for _, line := range lines {
+		cols = max(cols, len(line))
+	}
+	screenW, screenH := float64(cols)*cw, float64(len(lines))*lh
+
+	originX, originY := 0.0, 0.0
+	width, height := screenW, screenH
+	if opts.Frame {
+		originX, originY = svgPadding, svgTitleHeight
+		width, height = screenW+2*svgPadding, screenH+svgTitleHeight+svgPadding
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		svgNum(width), svgNum(height), svgNum(width), svgNum(height))
+
+	if opts.Frame {
+		fmt.Fprintf(&b, `<rect width="%s" height="%s" rx="6" fill="%s"/>`+"\n",
+			svgNum(width), svgNum(height), cssColor(palette.Background))
+		for i, c := range []string{"#ff5f56", "#ffbd2e", "#27c93f"} {
+			fmt.Fprintf(&b, `<circle cx="%s" cy="%s" r="6" fill="%s"/>`+"\n",
+				svgNum(svgPadding+6+float64(i)*20), svgNum(svgTitleHeight/2), c)
+		}
+		if opts.Title != "" {
+			fmt.Fprintf(&b, `<text x="%s" y="%s" fill="%s" font-family="%s" font-size="%s" text-anchor="middle">%s</text>`+"\n",
+				svgNum(width/2), svgNum(svgTitleHeight/2+fontSize/3), cssColor(palette.Foreground),
+				html.EscapeString(family), svgNum(fontSize), html.EscapeString(opts.Title))
+		}
+	} else {
+		fmt.Fprintf(&b, `<rect width="%s" height="%s" fill="%s"/>`+"\n",
+			svgNum(width), svgNum(height), cssColor(palette.Background))
+	}
+
+	fmt.Fprintf(&b, `<g font-family="%s" font-size="%s" xml:space="preserve">`+"\n",
+		html.EscapeString(family), svgNum(fontSize))
+
+	for y, line := range lines {
+		styles := make([]svgStyle, len(line))
+		for x, cell := range line {
+			styles[x] = svgStyleOf(cell, colors)
+		}
+
+		top := originY + float64(y)*lh
+		baseline := top + fontSize
+
+		for x := 0; x < len(line); {
+			run := x
+			for run < len(line) && styles[run] == styles[x] {
+				run++
+			}
+			style := styles[x]
+			left := originX + float64(x)*cw
+			runW := float64(run-x) * cw
+
+			if style.bg != palette.Background {
+				fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+					svgNum(left), svgNum(top), svgNum(runW), svgNum(lh), cssColor(style.bg))
+			}
+
+			var text strings.Builder
+			for _, cell := range line[x:run] {
+				switch {
+				case cell.WideCharSpacer:
+				case cell.Char == 0:
+					text.WriteByte(' ')
+				default:
+					text.WriteRune(cell.Char)
+				}
+			}
+			if content := strings.TrimRight(text.String(), " "); content != "" {
+				textW := float64(len([]rune(content))) * cw
+				for _, cell := range line[x:run] {
+					if cell.WideChar {
+						textW += cw
+					}
+				}
+				fmt.Fprintf(&b, `<text x="%s" y="%s" fill="%s"%s textLength="%s" lengthAdjust="spacingAndGlyphs">%s</text>`+"\n",
+					svgNum(left), svgNum(baseline), cssColor(style.fg), svgFontAttrs(style),
+					svgNum(textW), html.EscapeString(content))
+			}
+
+			if style.underline {
+				fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="1" fill="%s"/>`+"\n",
+					svgNum(left), svgNum(baseline+2), svgNum(runW), cssColor(style.fg))
+			}
+			if style.strikeout {
+				fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="1" fill="%s"/>`+"\n",
+					svgNum(left), svgNum(baseline-fontSize*0.3), svgNum(runW), cssColor(style.fg))
+			}
+
+			x = run
+		}
+	}
+
+	b.WriteString("</g>\n</svg>\n")
+	return b.String()
+}
+
+func svgStyleOf(cell Cell, colors ColorOptions) svgStyle {
+	fg, bg := cell.EffectiveColors(colors)
+	return svgStyle{
+		fg:        fg,
+		bg:        bg,
+		bold:      cell.Bold,
+		italic:    cell.Italic,
+		underline: cell.Underline,
+		strikeout: cell.Strikeout,
+	}
+}
+
+func svgFontAttrs(style svgStyle) string {
+	var attrs string
+	if style.bold {
+		attrs += ` font-weight="bold"`
+	}
+	if style.italic {
+		attrs += ` font-style="italic"`
+	}
+	return attrs
+}
+
+// svgNum formats a coordinate with at most two decimals
+func svgNum(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,67 @@
+package alacritty
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSVGIsWellFormed(t *testing.T) {
+	term := NewTerminal(20, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	if _, err := term.Write([]byte("a<b & \x1b[4;32mok\x1b[0m")); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	out, err := term.SVG(SVGOptions{Frame: true, Title: "bash & co"})
+	if err != nil {
+		t.Fatalf("Failed to export SVG: %v", err)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("SVG is not well-formed XML: %v\n%s", err, out)
+			}
+			break
+		}
+	}
+
+	again, err := term.SVG(SVGOptions{Frame: true, Title: "bash & co"})
+	if err != nil {
+		t.Fatalf("Failed to export SVG: %v", err)
+	}
+	if again != out {
+		t.Error("SVG export is not deterministic")
+	}
+}
+
+func TestSVGRuns(t *testing.T) {
+	green := plainCell('o')
+	green.FgColor, green.FgIndex, green.Underline = RGB{G: 255}, 2, true
+	inverse := plainCell('x')
+	inverse.Inverse = true
+
+	lines := [][]Cell{{plainCell('h'), plainCell('i'), green, inverse, plainCell(' ')}}
+	out := renderSVG(lines, DefaultPalette(), SVGOptions{FontSize: 10})
+
+	for _, expected := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="30" height="12" viewBox="0 0 30 12">`,
+		`<text x="0" y="10" fill="#ffffff" textLength="12" lengthAdjust="spacingAndGlyphs">hi</text>`,
+		`<text x="12" y="10" fill="#00ff00" textLength="6" lengthAdjust="spacingAndGlyphs">o</text>`,
+		`<rect x="12" y="12" width="6" height="1" fill="#00ff00"/>`,
+		`<rect x="18" y="0" width="6" height="12" fill="#ffffff"/>`,
+		`<text x="18" y="10" fill="#000000" textLength="6" lengthAdjust="spacingAndGlyphs">x</text>`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("SVG is missing %s\n%s", expected, out)
+		}
+	}
+}
@@ -9,8 +9,12 @@ package alacritty
 import "C"
 import (
 	"fmt"
+	"io"
 	"runtime"
+	"sync"
 	"unsafe"
+
+	"github.com/example/alacritty-go/color"
 )
 
 // Cell represents a terminal cell with character and formatting
@@ -22,32 +26,87 @@ type Cell struct {
 	Italic    bool
 	Underline bool
 	Inverse   bool
+	Strikeout bool
+	Dim       bool
+	Hidden    bool
+
+	// Wrapline is set on the last cell of a line that wrapped into the next
+	Wrapline bool
+
+	// WideChar is set on a double-width character, which occupies its own
+	// cell and the WideCharSpacer cell that follows it
+	WideChar       bool
+	WideCharSpacer bool
+
+	// FgIndex and BgIndex are the palette entries the colors came from:
+	// 0-255 for indexed colors, IndexForeground or IndexBackground for the
+	// default colors and IndexDirect for truecolor
+	FgIndex uint16
+	BgIndex uint16
+
+	// Hyperlink is the OSC 8 link of the cell, or the zero Hyperlink
+	Hyperlink Hyperlink
 }
 
-// RGB represents an RGB color
-type RGB struct {
-	R, G, B uint8
+// Hyperlink is the target of an OSC 8 hyperlink. Cells of one link share
+// the ID, which alacritty generates if the application gave none.
+type Hyperlink struct {
+	ID  string
+	URI string
 }
 
-// Terminal represents a terminal emulator instance
+// Special palette indices reported in Cell.FgIndex and Cell.BgIndex
+const (
+	IndexForeground uint16 = C.COLOR_INDEX_FOREGROUND
+	IndexBackground uint16 = C.COLOR_INDEX_BACKGROUND
+	IndexDirect     uint16 = C.COLOR_INDEX_DIRECT
+)
+
+// RGB represents an RGB color
+type RGB = color.RGB
+
+// Terminal represents a terminal emulator instance. It is safe for
+// concurrent use: methods that change the terminal take an exclusive lock,
+// and readers share a lock so that they never observe a Write or Resize in
+// progress.
 type Terminal struct {
+	mu  sync.RWMutex
 	ptr *C.CTerminal
+
+	// onWorkingDirectory is called by Write when the working directory
+	// changes from dir
+	onWorkingDirectory func(dir string)
+	dir                string
+	// clipboard handles OSC 52 requests if set
+	clipboard *clipboard
+	// replies receives the answers to the application
+	replies io.Writer
+}
+
+// writeEvents are the results of a write that Write handles after
+// releasing the lock, so that callbacks may use the terminal
+type writeEvents struct {
+	dir        string
+	dirChanged bool
+	replies    []byte
+	clipboard  []clipboardRequest
 }
 
-// NewTerminal creates a new terminal with the specified dimensions
+// NewTerminal creates a new terminal with the specified dimensions and the
+// default options. It returns nil where New returns an error.
 func NewTerminal(cols, rows uint32) *Terminal {
-	ptr := C.terminal_new(C.uint32_t(cols), C.uint32_t(rows))
-	if ptr == nil {
+	term, err := New(cols, rows)
+	if err != nil {
 		return nil
 	}
-	
-	term := &Terminal{ptr: ptr}
-	runtime.SetFinalizer(term, (*Terminal).Close)
 	return term
 }
 
 // Close frees the terminal resources
 func (t *Terminal) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if t.ptr != nil {
 		C.terminal_free(t.ptr)
 		t.ptr = nil
@@ -57,12 +116,38 @@ func (t *Terminal) Close() {
 
 // Write processes input bytes and returns the number of changed lines
 func (t *Terminal) Write(data []byte) (int, error) {
+	n, events, err := t.write(data)
+	if err != nil {
+		return n, err
+	}
+
+	if events.dirChanged {
+		t.onWorkingDirectory(events.dir)
+	}
+	if len(events.replies) > 0 {
+		if _, err := t.replies.Write(events.replies); err != nil {
+			return n, err
+		}
+	}
+	if err := t.handleClipboard(events.clipboard); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func (t *Terminal) write(data []byte) (int, writeEvents, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var events writeEvents
 	if t.ptr == nil {
-		return 0, fmt.Errorf("terminal is closed")
+		return 0, events, ErrClosed
 	}
 	
 	if len(data) == 0 {
-		return 0, nil
+		return 0, events, nil
 	}
 	
 	result := C.terminal_process_bytes(
@@ -72,38 +157,117 @@ func (t *Terminal) Write(data []byte) (int, error) {
 	)
 	
 	if result < 0 {
-		return 0, fmt.Errorf("failed to process bytes")
+		return 0, events, lastError("Write", result)
+	}
+
+	var err error
+	if t.onWorkingDirectory != nil {
+		if events.dir, err = t.workingDirectory(); err != nil {
+			return 0, events, err
+		}
+		if events.dir != t.dir {
+			t.dir = events.dir
+			events.dirChanged = true
+		}
+	}
+	if t.replies != nil {
+		if events.replies, err = t.takeReplies(); err != nil {
+			return 0, events, err
+		}
+	}
+	if t.clipboard != nil {
+		if events.clipboard, err = t.clipboardRequests(); err != nil {
+			return 0, events, err
+		}
 	}
 	
-	return int(result), nil
+	return int(result), events, nil
+}
+
+// takeReplies takes the answers to the application. The caller holds the
+// lock.
+func (t *Terminal) takeReplies() ([]byte, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	result := C.terminal_take_replies(t.ptr, nil, 0)
+	if result <= 0 {
+		if result < 0 {
+			return nil, lastError("Write", result)
+		}
+		return nil, nil
+	}
+
+	buf := make([]byte, result)
+	C.terminal_take_replies(t.ptr, (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
+	return buf, nil
 }
 
 // GetCell returns the cell at the specified position
 func (t *Terminal) GetCell(x, y uint32) (Cell, error) {
-	if t.ptr == nil {
-		return Cell{}, fmt.Errorf("terminal is closed")
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	cols, rows, err := t.size()
+	if err != nil {
+		return Cell{}, err
+	}
+	if x >= cols || y >= rows {
+		detail := fmt.Sprintf("cell (%d, %d) outside the %dx%d screen", x, y, cols, rows)
+		return Cell{}, &Error{Op: "GetCell", Code: CodeOutOfBounds, Detail: detail}
 	}
 	
 	cCell := C.terminal_get_cell(t.ptr, C.uint32_t(x), C.uint32_t(y))
+	if cCell.flags&C.CELL_FLAG_HYPERLINK != 0 {
+		line, err := t.line(y)
+		if err != nil {
+			return Cell{}, err
+		}
+		return line[x], nil
+	}
 	
+	return cellFromC(cCell), nil
+}
+
+// cellFromC converts a C cell into its Go representation
+func cellFromC(cCell C.CCell) Cell {
 	return Cell{
-		Char:      rune(cCell.c),
-		FgColor:   RGB{R: uint8(cCell.fg_r), G: uint8(cCell.fg_g), B: uint8(cCell.fg_b)},
-		BgColor:   RGB{R: uint8(cCell.bg_r), G: uint8(cCell.bg_g), B: uint8(cCell.bg_b)},
-		Bold:      (cCell.flags & C.CELL_FLAG_BOLD) != 0,
-		Italic:    (cCell.flags & C.CELL_FLAG_ITALIC) != 0,
-		Underline: (cCell.flags & C.CELL_FLAG_UNDERLINE) != 0,
-		Inverse:   (cCell.flags & C.CELL_FLAG_INVERSE) != 0,
-	}, nil
+		Char:           rune(cCell.c),
+		FgColor:        RGB{R: uint8(cCell.fg_r), G: uint8(cCell.fg_g), B: uint8(cCell.fg_b)},
+		BgColor:        RGB{R: uint8(cCell.bg_r), G: uint8(cCell.bg_g), B: uint8(cCell.bg_b)},
+		Bold:           (cCell.flags & C.CELL_FLAG_BOLD) != 0,
+		Italic:         (cCell.flags & C.CELL_FLAG_ITALIC) != 0,
+		Underline:      (cCell.flags & C.CELL_FLAG_UNDERLINE) != 0,
+		Inverse:        (cCell.flags & C.CELL_FLAG_INVERSE) != 0,
+		Strikeout:      (cCell.flags & C.CELL_FLAG_STRIKEOUT) != 0,
+		Dim:            (cCell.flags & C.CELL_FLAG_DIM) != 0,
+		Hidden:         (cCell.flags & C.CELL_FLAG_HIDDEN) != 0,
+		Wrapline:       (cCell.flags & C.CELL_FLAG_WRAPLINE) != 0,
+		WideChar:       (cCell.flags & C.CELL_FLAG_WIDE_CHAR) != 0,
+		WideCharSpacer: (cCell.flags & C.CELL_FLAG_WIDE_CHAR_SPACER) != 0,
+		FgIndex:        uint16(cCell.fg_index),
+		BgIndex:        uint16(cCell.bg_index),
+	}
 }
 
 // GetLine returns all cells for a specific line
 func (t *Terminal) GetLine(y uint32) ([]Cell, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.line(y)
+}
+
+// line is GetLine for callers holding the lock
+func (t *Terminal) line(y uint32) ([]Cell, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
 	if t.ptr == nil {
-		return nil, fmt.Errorf("terminal is closed")
+		return nil, ErrClosed
 	}
 	
-	cols, _, err := t.GetSize()
+	cols, _, err := t.size()
 	if err != nil {
 		return nil, err
 	}
@@ -119,36 +283,168 @@ func (t *Terminal) GetLine(y uint32) ([]Cell, error) {
 	)
 	
 	if result < 0 {
-		return nil, fmt.Errorf("failed to get line")
+		return nil, lastError("GetLine", result)
 	}
 	
 	// Convert C cells to Go cells
 	cells := make([]Cell, result)
 	for i := 0; i < int(result); i++ {
-		cCell := cCells[i]
-		cells[i] = Cell{
-			Char:      rune(cCell.c),
-			FgColor:   RGB{R: uint8(cCell.fg_r), G: uint8(cCell.fg_g), B: uint8(cCell.fg_b)},
-			BgColor:   RGB{R: uint8(cCell.bg_r), G: uint8(cCell.bg_g), B: uint8(cCell.bg_b)},
-			Bold:      (cCell.flags & C.CELL_FLAG_BOLD) != 0,
-			Italic:    (cCell.flags & C.CELL_FLAG_ITALIC) != 0,
-			Underline: (cCell.flags & C.CELL_FLAG_UNDERLINE) != 0,
-			Inverse:   (cCell.flags & C.CELL_FLAG_INVERSE) != 0,
+		cells[i] = cellFromC(cCells[i])
+	}
+	if hasHyperlinks(cCells) {
+		if err := t.hyperlinks("GetLine", int32(y), cells); err != nil {
+			return nil, err
 		}
 	}
 	
 	return cells, nil
 }
 
+// HistorySize returns the number of lines in the scrollback history
+func (t *Terminal) HistorySize() (uint32, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.historySize()
+}
+
+// historySize is HistorySize for callers holding the lock
+func (t *Terminal) historySize() (uint32, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return 0, ErrClosed
+	}
+
+	result := C.terminal_get_history_size(t.ptr)
+	if result < 0 {
+		return 0, lastError("HistorySize", result)
+	}
+
+	return uint32(result), nil
+}
+
+// ClearHistory drops the scrollback history, as an application does with
+// ESC [ 3 J
+func (t *Terminal) ClearHistory() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return ErrClosed
+	}
+
+	if result := C.terminal_clear_history(t.ptr); result != 0 {
+		return lastError("ClearHistory", result)
+	}
+	return nil
+}
+
+// GetHistoryLine returns all cells for a scrollback line, where line 0 is
+// the oldest line in the history
+func (t *Terminal) GetHistoryLine(n uint32) ([]Cell, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.historyLine(n)
+}
+
+// historyLine is GetHistoryLine for callers holding the lock
+func (t *Terminal) historyLine(n uint32) ([]Cell, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return nil, ErrClosed
+	}
+
+	cols, _, err := t.size()
+	if err != nil {
+		return nil, err
+	}
+
+	cCells := make([]C.CCell, cols)
+
+	result := C.terminal_get_history_line(
+		t.ptr,
+		C.uint32_t(n),
+		(*C.CCell)(unsafe.Pointer(&cCells[0])),
+		C.size_t(cols),
+	)
+
+	if result < 0 {
+		return nil, lastError("GetHistoryLine", result)
+	}
+
+	cells := make([]Cell, result)
+	for i := 0; i < int(result); i++ {
+		cells[i] = cellFromC(cCells[i])
+	}
+	if hasHyperlinks(cCells) {
+		history, err := t.historySize()
+		if err != nil {
+			return nil, err
+		}
+		if err := t.hyperlinks("GetHistoryLine", int32(n)-int32(history), cells); err != nil {
+			return nil, err
+		}
+	}
+
+	return cells, nil
+}
+
+func hasHyperlinks(cCells []C.CCell) bool {
+	for _, c := range cCells {
+		if c.flags&C.CELL_FLAG_HYPERLINK != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hyperlinks sets the hyperlinks of the cells of a line, where negative
+// lines are in the history. The caller holds the lock.
+func (t *Terminal) hyperlinks(op string, line int32, cells []Cell) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// There is at most one span per cell
+	spans := make([]C.CHyperlinkSpan, len(cells))
+	result := C.terminal_get_hyperlinks(t.ptr, C.int32_t(line), &spans[0], C.size_t(len(spans)))
+	if result < 0 {
+		return lastError(op, result)
+	}
+
+	for _, span := range spans[:min(int(result), len(spans))] {
+		link := Hyperlink{
+			ID:  C.GoStringN((*C.char)(unsafe.Pointer(span.id)), C.int(span.id_len)),
+			URI: C.GoStringN((*C.char)(unsafe.Pointer(span.uri)), C.int(span.uri_len)),
+		}
+		for x := int(span.start); x < int(span.end) && x < len(cells); x++ {
+			cells[x].Hyperlink = link
+		}
+	}
+
+	return nil
+}
+
 // Resize changes the terminal size
 func (t *Terminal) Resize(cols, rows uint32) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
 	if t.ptr == nil {
-		return fmt.Errorf("terminal is closed")
+		return ErrClosed
 	}
 	
 	result := C.terminal_resize(t.ptr, C.uint32_t(cols), C.uint32_t(rows))
 	if result != 0 {
-		return fmt.Errorf("failed to resize terminal")
+		return lastError("Resize", result)
 	}
 	
 	return nil
@@ -156,14 +452,25 @@ func (t *Terminal) Resize(cols, rows uint32) error {
 
 // GetSize returns the current terminal size
 func (t *Terminal) GetSize() (cols, rows uint32, err error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.size()
+}
+
+// size is GetSize for callers holding the lock
+func (t *Terminal) size() (cols, rows uint32, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
 	if t.ptr == nil {
-		return 0, 0, fmt.Errorf("terminal is closed")
+		return 0, 0, ErrClosed
 	}
 	
 	var cCols, cRows C.uint32_t
 	result := C.terminal_get_size(t.ptr, &cCols, &cRows)
 	if result != 0 {
-		return 0, 0, fmt.Errorf("failed to get terminal size")
+		return 0, 0, lastError("GetSize", result)
 	}
 	
 	return uint32(cCols), uint32(cRows), nil
@@ -171,29 +478,348 @@ func (t *Terminal) GetSize() (cols, rows uint32, err error) {
 
 // GetCursor returns the current cursor position
 func (t *Terminal) GetCursor() (x, y uint32, err error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.cursor()
+}
+
+// cursor is GetCursor for callers holding the lock
+func (t *Terminal) cursor() (x, y uint32, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
 	if t.ptr == nil {
-		return 0, 0, fmt.Errorf("terminal is closed")
+		return 0, 0, ErrClosed
 	}
 	
 	var cX, cY C.uint32_t
 	result := C.terminal_get_cursor(t.ptr, &cX, &cY)
 	if result != 0 {
-		return 0, 0, fmt.Errorf("failed to get cursor position")
+		return 0, 0, lastError("GetCursor", result)
 	}
 	
 	return uint32(cX), uint32(cY), nil
 }
 
+// wrapPending reports whether the cursor waits past the last column, where
+// the next character first wraps to the next line
+func (t *Terminal) wrapPending() (bool, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return false, ErrClosed
+	}
+
+	var cPending C.uint8_t
+	result := C.terminal_get_wrap_pending(t.ptr, &cPending)
+	if result != 0 {
+		return false, lastError("Snapshot", result)
+	}
+
+	return cPending != 0, nil
+}
+
+// scrollRegion returns the scrolling region as its first line and the line
+// after its last, which are 0 and the number of rows without a region
+func (t *Terminal) scrollRegion() (top, bottom uint32, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return 0, 0, ErrClosed
+	}
+
+	var cTop, cBottom C.uint32_t
+	result := C.terminal_get_scroll_region(t.ptr, &cTop, &cBottom)
+	if result != 0 {
+		return 0, 0, lastError("Snapshot", result)
+	}
+
+	return uint32(cTop), uint32(cBottom), nil
+}
+
+// showPrimary shows the primary screen behind the alternate screen to the
+// methods reading the screen, or the alternate screen again. Callers hold
+// the exclusive lock until the alternate screen is shown again.
+func (t *Terminal) showPrimary(show bool) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return ErrClosed
+	}
+
+	var result C.int
+	if show {
+		result = C.terminal_begin_primary(t.ptr)
+	} else {
+		result = C.terminal_end_primary(t.ptr)
+	}
+	if result != 0 {
+		return lastError("Snapshot", result)
+	}
+	return nil
+}
+
+// Pen returns the colors and attributes applied to newly written text
+func (t *Terminal) Pen() (Cell, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.pen()
+}
+
+// pen is Pen for callers holding the lock
+func (t *Terminal) pen() (Cell, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return Cell{}, ErrClosed
+	}
+
+	var cCell C.CCell
+	result := C.terminal_get_pen(t.ptr, &cCell)
+	if result != 0 {
+		return Cell{}, lastError("Pen", result)
+	}
+
+	return cellFromC(cCell), nil
+}
+
+// Title returns the window title set with OSC 0 or OSC 2
+func (t *Terminal) Title() (string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.title()
+}
+
+// title is Title for callers holding the lock
+func (t *Terminal) title() (string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return "", ErrClosed
+	}
+
+	result := C.terminal_get_title(t.ptr, nil, 0)
+	if result < 0 {
+		return "", lastError("Title", result)
+	}
+	if result == 0 {
+		return "", nil
+	}
+
+	buf := make([]byte, result)
+	C.terminal_get_title(t.ptr, (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.size_t(len(buf)))
+
+	return string(buf), nil
+}
+
+// Mode is a set of terminal modes
+type Mode uint32
+
+// Terminal modes reported by Modes
+const (
+	ModeShowCursor           Mode = C.MODE_SHOW_CURSOR
+	ModeAppCursor            Mode = C.MODE_APP_CURSOR
+	ModeAppKeypad            Mode = C.MODE_APP_KEYPAD
+	ModeMouseReportClick     Mode = C.MODE_MOUSE_REPORT_CLICK
+	ModeBracketedPaste       Mode = C.MODE_BRACKETED_PASTE
+	ModeSGRMouse             Mode = C.MODE_SGR_MOUSE
+	ModeMouseMotion          Mode = C.MODE_MOUSE_MOTION
+	ModeLineWrap             Mode = C.MODE_LINE_WRAP
+	ModeLineFeedNewLine      Mode = C.MODE_LINE_FEED_NEW_LINE
+	ModeOrigin               Mode = C.MODE_ORIGIN
+	ModeInsert               Mode = C.MODE_INSERT
+	ModeFocusInOut           Mode = C.MODE_FOCUS_IN_OUT
+	ModeAltScreen            Mode = C.MODE_ALT_SCREEN
+	ModeMouseDrag            Mode = C.MODE_MOUSE_DRAG
+	ModeUTF8Mouse            Mode = C.MODE_UTF8_MOUSE
+	ModeAlternateScroll      Mode = C.MODE_ALTERNATE_SCROLL
+	ModeUrgencyHints         Mode = C.MODE_URGENCY_HINTS
+	ModeDisambiguateEscCodes Mode = C.MODE_DISAMBIGUATE_ESC_CODES
+)
+
+// Modes returns the currently active terminal modes
+func (t *Terminal) Modes() (Mode, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.modes()
+}
+
+// modes is Modes for callers holding the lock
+func (t *Terminal) modes() (Mode, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return 0, ErrClosed
+	}
+
+	var cMode C.uint32_t
+	result := C.terminal_get_mode(t.ptr, &cMode)
+	if result != 0 {
+		return 0, lastError("Modes", result)
+	}
+
+	return Mode(cMode), nil
+}
+
+// MarshalBinary encodes the complete terminal state: both screens with the
+// scrollback, cursor, saved cursor, modes, charsets, tab stops, scrolling
+// region, title and palette, as well as an escape sequence the parser is
+// in the middle of and the output held back for a synchronized update.
+// Encoding does not change the terminal.
+func (t *Terminal) MarshalBinary() ([]byte, error) {
+	return t.marshal(true)
+}
+
+func (t *Terminal) marshal(history bool) ([]byte, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Saving swaps the screens for a moment to read the one behind the
+	// alternate screen
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ptr == nil {
+		return nil, ErrClosed
+	}
+
+	var flags C.uint32_t
+	if !history {
+		flags |= C.SAVE_STATE_NO_HISTORY
+	}
+
+	var data *C.uint8_t
+	var size C.size_t
+	result := C.terminal_save_state(t.ptr, flags, &data, &size)
+	if result != 0 {
+		return nil, lastError("MarshalBinary", result)
+	}
+	defer C.terminal_free_state(data, size)
+
+	return C.GoBytes(unsafe.Pointer(data), C.int(size)), nil
+}
+
+// UnmarshalBinary replaces the terminal state, including its size, with
+// state encoded by MarshalBinary. The terminal keeps the options it was
+// created with, such as its scrollback size and maximum size, so it must be
+// created with New or NewTerminal; a zero Terminal returns ErrClosed.
+func (t *Terminal) UnmarshalBinary(data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return ErrClosed
+	}
+	if len(data) == 0 {
+		return &Error{Op: "UnmarshalBinary", Code: CodeInvalidState, Detail: "empty state"}
+	}
+
+	result := C.terminal_restore_state(
+		t.ptr,
+		(*C.uint8_t)(unsafe.Pointer(&data[0])),
+		C.size_t(len(data)),
+	)
+	if result != 0 {
+		return lastError("UnmarshalBinary", result)
+	}
+
+	return nil
+}
+
+// Checkpoint identifies a terminal state saved by Terminal.Checkpoint
+type Checkpoint struct {
+	id uint64
+}
+
+// Checkpoint marks the current state so that Rollback can return to it.
+// Taking a checkpoint copies nothing. The screens are copied when the
+// terminal changes next, and checkpoints without changes in between share
+// that copy. The scrollback is shared with the terminal: lines are copied
+// into the checkpoint only as they leave the scrollback, and all lines still
+// shared are copied before Resize, ClearHistory or a reset rewrites it.
+func (t *Terminal) Checkpoint() (Checkpoint, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return Checkpoint{}, ErrClosed
+	}
+
+	var id C.uint64_t
+	result := C.terminal_checkpoint(t.ptr, &id)
+	if result != 0 {
+		return Checkpoint{}, lastError("Checkpoint", result)
+	}
+
+	return Checkpoint{id: uint64(id)}, nil
+}
+
+// Rollback restores the state, including the size, of a checkpoint. The
+// checkpoint stays valid until it is released.
+func (t *Terminal) Rollback(cp Checkpoint) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return ErrClosed
+	}
+
+	result := C.terminal_rollback(t.ptr, C.uint64_t(cp.id))
+	if result != 0 {
+		return lastError("Rollback", result)
+	}
+
+	return nil
+}
+
+// ReleaseCheckpoint frees the state held by a checkpoint
+func (t *Terminal) ReleaseCheckpoint(cp Checkpoint) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return ErrClosed
+	}
+
+	result := C.terminal_release_checkpoint(t.ptr, C.uint64_t(cp.id))
+	if result != 0 {
+		return lastError("ReleaseCheckpoint", result)
+	}
+
+	return nil
+}
+
 // String returns a string representation of the terminal content
 func (t *Terminal) String() string {
-	_, rows, err := t.GetSize()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, rows, err := t.size()
 	if err != nil {
 		return ""
 	}
 	
 	var result string
 	for y := uint32(0); y < rows; y++ {
-		line, err := t.GetLine(y)
+		line, err := t.line(y)
 		if err != nil {
 			continue
 		}
@@ -1,7 +1,9 @@
 package alacritty
 
 import (
+	"errors"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -316,6 +318,8 @@ func TestANSITextFormatting(t *testing.T) {
 		expectedItalic bool
 		expectedUnder  bool
 		expectedChar   rune
+		// Colors the cell is drawn with, white on black if unset
+		expectedFg, expectedBg *RGB
 	}{
 		{
 			name:         "Bold text",
@@ -353,6 +357,21 @@ func TestANSITextFormatting(t *testing.T) {
 			expectedBold: false,
 			expectedChar: 'N',
 		},
+		{
+			name:         "Inverse text",
+			input:        "\x1b[7mR",
+			checkPos:     0,
+			expectedChar: 'R',
+			expectedFg:   &RGB{R: 0, G: 0, B: 0},
+			expectedBg:   &RGB{R: 255, G: 255, B: 255},
+		},
+		{
+			name:         "Hidden text",
+			input:        "\x1b[8mH",
+			checkPos:     0,
+			expectedChar: 'H',
+			expectedFg:   &RGB{R: 0, G: 0, B: 0},
+		},
 	}
 
 	for _, tt := range tests {
@@ -386,6 +405,17 @@ func TestANSITextFormatting(t *testing.T) {
 			if cell.Underline != tt.expectedUnder {
 				t.Errorf("Underline: expected %v, got %v", tt.expectedUnder, cell.Underline)
 			}
+
+			expectedFg, expectedBg := RGB{R: 255, G: 255, B: 255}, RGB{R: 0, G: 0, B: 0}
+			if tt.expectedFg != nil {
+				expectedFg = *tt.expectedFg
+			}
+			if tt.expectedBg != nil {
+				expectedBg = *tt.expectedBg
+			}
+			if fg, bg := cell.EffectiveColors(ColorOptions{}); fg != expectedFg || bg != expectedBg {
+				t.Errorf("Colors: expected %v on %v, got %v on %v", expectedFg, expectedBg, fg, bg)
+			}
 		})
 	}
 }
@@ -453,10 +483,10 @@ func TestComplexANSISequences(t *testing.T) {
 		bold     bool
 		underline bool
 	}{
-		{0, 'N', RGB{255, 255, 255}, false, false}, // "Normal "
-		{7, 'B', RGB{255, 0, 0}, true, false},      // "Bold Red"
-		{16, 'U', RGB{0, 255, 0}, false, true},     // "Under Green"
-		{28, 'N', RGB{255, 255, 255}, false, false}, // " Normal"
+		{0, 'N', RGB{R: 255, G: 255, B: 255}, false, false}, // "Normal "
+		{7, 'B', RGB{R: 255, G: 0, B: 0}, true, false},      // "Bold Red"
+		{16, 'U', RGB{R: 0, G: 255, B: 0}, false, true},     // "Under Green"
+		{28, 'N', RGB{R: 255, G: 255, B: 255}, false, false}, // " Normal"
 	}
 
 	for i, tt := range tests {
@@ -565,6 +595,94 @@ func TestUnicodeSupport(t *testing.T) {
 	}
 }
 
+func TestConcurrentWriteAndRead(t *testing.T) {
+	term := NewTerminal(40, 10)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+	defer term.Close()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// One writer parses output and resizes like a PTY goroutine would
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			if _, err := term.Write([]byte("\x1b[1;32mconcurrent output line\x1b[0m\r\n")); err != nil {
+				t.Errorf("Write: %v", err)
+				return
+			}
+			if i%50 == 0 {
+				cols, rows := uint32(40), uint32(10)
+				if i%100 == 0 {
+					cols, rows = 60, 20
+				}
+				if err := term.Resize(cols, rows); err != nil {
+					t.Errorf("Resize: %v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				// A snapshot is taken in one step, so its size, lines and
+				// cursor always agree
+				s, err := term.Snapshot()
+				if err != nil {
+					t.Errorf("Snapshot: %v", err)
+					return
+				}
+				cols, rows := s.Size()
+				if (cols != 40 || rows != 10) && (cols != 60 || rows != 20) {
+					t.Errorf("Unexpected size %dx%d", cols, rows)
+					return
+				}
+				for y := uint32(0); y < rows; y++ {
+					if line := s.Line(y); uint32(len(line)) != cols {
+						t.Errorf("Line %d has %d cells, expected %d", y, len(line), cols)
+						return
+					}
+				}
+				if x, y := s.Cursor(); x >= cols || y >= rows {
+					t.Errorf("Cursor (%d, %d) outside the %dx%d screen", x, y, cols, rows)
+					return
+				}
+
+				// Separate calls may see a resize in between, which only
+				// moves lines out of bounds
+				if _, err := term.GetLine(rows - 1); err != nil && !errors.Is(err, ErrOutOfBounds) {
+					t.Errorf("GetLine: %v", err)
+					return
+				}
+				if _, _, err := term.GetCursor(); err != nil {
+					t.Errorf("GetCursor: %v", err)
+					return
+				}
+				if _, err := term.Modes(); err != nil {
+					t.Errorf("Modes: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
 func BenchmarkTerminalWrite(b *testing.B) {
 	term := NewTerminal(80, 24)
 	if term == nil {
@@ -0,0 +1,27 @@
+//go:build alacritty_test_panic
+
+package alacritty
+
+/*
+#include "alacritty_ffi.h"
+*/
+import "C"
+import "runtime"
+
+// testPanic makes the library panic while handling the terminal. The
+// library must be built with its test-panic feature, as make test does.
+func (t *Terminal) testPanic() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if t.ptr == nil {
+		return ErrClosed
+	}
+
+	if result := C.terminal_test_panic(t.ptr); result != 0 {
+		return lastError("testPanic", result)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build alacritty_test_panic
+
+package alacritty
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPanicPoisonsTerminal(t *testing.T) {
+	term := NewTerminal(10, 3)
+	if term == nil {
+		t.Fatal("Failed to create terminal")
+	}
+
+	err := term.testPanic()
+	var e *Error
+	if !errors.Is(err, ErrPoisoned) || !errors.As(err, &e) || e.Detail != "panic: test panic" {
+		t.Fatalf("Expected ErrPoisoned with the panic message, got %v", err)
+	}
+
+	// Every later call fails, still with the panic message
+	if _, err := term.Write([]byte("x")); !errors.Is(err, ErrPoisoned) || !errors.As(err, &e) || e.Detail != "panic: test panic" {
+		t.Errorf("Write: expected ErrPoisoned with the panic message, got %v", err)
+	}
+	if _, err := term.GetLine(0); !errors.Is(err, ErrPoisoned) {
+		t.Errorf("GetLine: expected ErrPoisoned, got %v", err)
+	}
+
+	// Close frees the poisoned terminal
+	term.Close()
+	if _, err := term.Write([]byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close: expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package alacritty
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// StripANSI returns a reader of the text of r without escape sequences and
+// controls. Line feeds, carriage returns and tabs are kept, so progress
+// bars still overwrite themselves; LineWriter collapses them instead.
+func StripANSI(r io.Reader) io.Reader {
+	return &stripReader{r: r, chunk: make([]byte, 32*1024)}
+}
+
+type stripReader struct {
+	r      io.Reader
+	parser *Parser
+	chunk  []byte
+	// text holds stripped text not yet read
+	text []byte
+	err  error
+}
+
+func (s *stripReader) Read(b []byte) (int, error) {
+	for len(s.text) == 0 && s.err == nil {
+		s.err = s.fill()
+	}
+
+	n := copy(b, s.text)
+	s.text = s.text[n:]
+	if len(s.text) > 0 {
+		return n, nil
+	}
+	return n, s.err
+}
+
+// fill strips the next chunk of the underlying reader into text
+func (s *stripReader) fill() error {
+	if s.parser == nil {
+		parser, err := NewParser()
+		if err != nil {
+			return err
+		}
+		s.parser = parser
+	}
+
+	n, readErr := s.r.Read(s.chunk)
+	events, err := s.parser.Parse(s.chunk[:n])
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		switch e := e.(type) {
+		case PrintEvent:
+			s.text = append(s.text, e.Text...)
+		case ExecuteEvent:
+			if e.Byte == '\n' || e.Byte == '\r' || e.Byte == '\t' {
+				s.text = append(s.text, e.Byte)
+			}
+		}
+	}
+
+	if readErr != nil {
+		s.parser.Close()
+	}
+	return readErr
+}
+
+// LineWriterOptions controls the terminal of a LineWriter
+type LineWriterOptions struct {
+	// Cols and Rows are the size of the terminal, 200x50 if zero. Lines
+	// wider than the terminal are joined again.
+	Cols, Rows uint32
+}
+
+// lineWriterChunk is the most input a LineWriter passes to the terminal at
+// once, so that the lines it scrolls fit in the scrollback
+const lineWriterChunk = 1024
+
+// LineWriter turns terminal output into the text that remains on screen.
+// It feeds a terminal and writes each line to the underlying writer once
+// it is final, when it scrolls off the screen or the screen is cleared, so
+// that progress bars redrawn with carriage returns and lines rewritten by
+// moving the cursor collapse into their last state. Lines are written
+// without trailing blanks.
+type LineWriter struct {
+	w    io.Writer
+	term *Terminal
+	// parser finds ESC [ 3 J in the input, which drops the history before
+	// its lines are written
+	parser *Parser
+	// open is set when the last line written wraps into a line that is
+	// still on screen
+	open bool
+}
+
+// NewLineWriter creates a LineWriter writing lines to w. Close it to write
+// the lines left on the screen.
+func NewLineWriter(w io.Writer, opts LineWriterOptions) (*LineWriter, error) {
+	cols, rows := opts.Cols, opts.Rows
+	if cols == 0 {
+		cols = 200
+	}
+	if rows == 0 {
+		rows = 50
+	}
+
+	term, err := New(cols, rows)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := NewParser()
+	if err != nil {
+		term.Close()
+		return nil, err
+	}
+	return &LineWriter{w: w, term: term, parser: parser}, nil
+}
+
+// Write feeds data to the terminal and writes the lines it finalized
+func (l *LineWriter) Write(data []byte) (int, error) {
+	for written := 0; written < len(data); {
+		chunk := data[written:min(len(data), written+lineWriterChunk)]
+		if err := l.feed(chunk); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+
+		if err := l.flushHistory(); err != nil {
+			return written, err
+		}
+	}
+	return len(data), nil
+}
+
+// feed passes data to the terminal. Clearing the screen scrolls it into
+// the history, which ESC [ 3 J then drops, so the terminal gets that
+// sequence without its final byte until the history is written.
+func (l *LineWriter) feed(data []byte) error {
+	start := 0
+	for parsed := 0; parsed < len(data); {
+		end := len(data)
+		if i := bytes.IndexByte(data[parsed:], 'J'); i >= 0 {
+			end = parsed + i + 1
+		}
+		events, err := l.parser.Parse(data[parsed:end])
+		if err != nil {
+			return err
+		}
+		parsed = end
+		if len(events) == 0 || !clearsHistory(events[len(events)-1]) {
+			continue
+		}
+
+		if _, err := l.term.Write(data[start : end-1]); err != nil {
+			return err
+		}
+		if err := l.flushHistory(); err != nil {
+			return err
+		}
+		start = end - 1
+	}
+
+	_, err := l.term.Write(data[start:])
+	return err
+}
+
+// clearsHistory reports whether e is ESC [ 3 J
+func clearsHistory(e Event) bool {
+	csi, ok := e.(CSIEvent)
+	return ok && csi.Final == 'J' && len(csi.Intermediates) == 0 && !csi.Ignored &&
+		len(csi.Params) > 0 && len(csi.Params[0]) > 0 && csi.Params[0][0] == 3
+}
+
+// flushHistory writes the lines that scrolled into the history and drops
+// them from it
+func (l *LineWriter) flushHistory() error {
+	history, err := l.term.HistorySize()
+	if err != nil || history == 0 {
+		return err
+	}
+
+	text, err := l.term.RegionText(Region{Start: Point{Line: -int(history)}})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(l.w, text); err != nil {
+		return err
+	}
+	l.open = !strings.HasSuffix(text, "\n")
+
+	return l.term.ClearHistory()
+}
+
+// Close writes the lines left on the screen, up to the last one that is not
+// blank, and frees the terminal
+func (l *LineWriter) Close() error {
+	defer l.term.Close()
+	defer l.parser.Close()
+
+	if err := l.flushHistory(); err != nil {
+		return err
+	}
+
+	cols, rows, err := l.term.GetSize()
+	if err != nil {
+		return err
+	}
+	text, err := l.term.RegionText(Region{End: Point{Line: int(rows) - 1, Column: cols}})
+	if err != nil {
+		return err
+	}
+
+	text = strings.TrimRight(text, " \n")
+	if text == "" && !l.open {
+		return nil
+	}
+	_, err = io.WriteString(l.w, text+"\n")
+	return err
+}
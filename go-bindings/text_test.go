@@ -0,0 +1,84 @@
+package alacritty
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStripANSI(t *testing.T) {
+	input := "\x1b]0;npm install\x07\x1b[1m\x1b[32madded\x1b[0m 12 packages\r\n\x07\tdone é\r\n"
+	// Read one byte at a time, splitting every sequence across chunks
+	out, err := io.ReadAll(StripANSI(iotest.OneByteReader(strings.NewReader(input))))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	if expected := "added 12 packages\r\n\tdone é\r\n"; string(out) != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
+
+func TestLineWriterCollapsesProgress(t *testing.T) {
+	var out bytes.Buffer
+	lw, err := NewLineWriter(&out, LineWriterOptions{Cols: 20, Rows: 5})
+	if err != nil {
+		t.Fatalf("NewLineWriter: %v", err)
+	}
+
+	lw.Write([]byte("fetching\r\n\x1b[32m 10%\x1b[0m\r 55%\r100%\r\ndone"))
+	if err := lw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if expected := "fetching\n100%\ndone\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestLineWriterScrollAndClear(t *testing.T) {
+	var out bytes.Buffer
+	lw, err := NewLineWriter(&out, LineWriterOptions{Cols: 10, Rows: 3})
+	if err != nil {
+		t.Fatalf("NewLineWriter: %v", err)
+	}
+
+	for _, s := range []string{
+		// A step rewritten in place once it finishes
+		"#1 build\r\n#2 run\x1b[1A\r\x1b[2K#1 DONE\x1b[1B\r\n",
+		// A line wider than the terminal, wrapped and joined again
+		"0123456789abcdef\r\n",
+		"\x1b[2J\x1b[Hafter\r\n",
+	} {
+		lw.Write([]byte(s))
+	}
+	if out.String() != "#1 DONE\n#2 run\n0123456789abcdef\n" {
+		t.Errorf("Expected the lines that left the screen, got %q", out.String())
+	}
+
+	if err := lw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if expected := "#1 DONE\n#2 run\n0123456789abcdef\nafter\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestLineWriterClearScrollback(t *testing.T) {
+	var out bytes.Buffer
+	lw, err := NewLineWriter(&out, LineWriterOptions{Cols: 10, Rows: 3})
+	if err != nil {
+		t.Fatalf("NewLineWriter: %v", err)
+	}
+
+	// clear moves the screen into the history and drops it in one write
+	lw.Write([]byte("a\r\nb\x1b[H\x1b[2J\x1b[3Jc"))
+	if err := lw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if expected := "a\nb\nc\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
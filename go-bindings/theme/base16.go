@@ -0,0 +1,95 @@
+package theme
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/example/alacritty-go/color"
+)
+
+// base16ANSI are the base16 colors of the 16 ANSI colors, following the
+// terminal templates of base16
+var base16ANSI = [16]int{
+	0x00, 0x08, 0x0B, 0x0A, 0x0D, 0x0E, 0x0C, 0x05,
+	0x03, 0x08, 0x0B, 0x0A, 0x0D, 0x0E, 0x0C, 0x07,
+}
+
+// ParseBase16 reads a base16 scheme, in the original format with the
+// colors at the top level or in the newer one with them under palette.
+// All 16 colors base00 to base0F are required.
+func ParseBase16(r io.Reader) (*Theme, error) {
+	var base [16]*color.RGB
+	var name string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		key, value, ok := yamlField(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		switch {
+		case key == "scheme" || key == "name":
+			name = value
+		case len(key) == 6 && strings.HasPrefix(key, "base0"):
+			var i int
+			if _, err := fmt.Sscanf(key[5:], "%x", &i); err != nil {
+				continue
+			}
+			c, err := parseColor(value)
+			if err != nil {
+				return nil, fmt.Errorf("theme: line %d: %s: %w", n, key, err)
+			}
+			base[i] = &c
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, c := range base {
+		if c == nil {
+			return nil, fmt.Errorf("theme: missing base0%X", i)
+		}
+	}
+
+	th := &Theme{
+		Name: name,
+		Palette: color.Palette{
+			Foreground: *base[0x05],
+			Background: *base[0x00],
+		},
+		Cursor:              base[0x05],
+		CursorText:          base[0x00],
+		SelectionBackground: base[0x02],
+		SelectionText:       base[0x05],
+	}
+	for i, b := range base16ANSI {
+		th.Palette.ANSI[i] = *base[b]
+	}
+	return th, nil
+}
+
+// yamlField splits a YAML line of the form key: value, dropping comments
+// and the quotes around the value. Nesting is ignored, since the keys of a
+// scheme are unique.
+func yamlField(line string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(strings.TrimSpace(line), ":")
+	if !ok || key == "" || strings.HasPrefix(key, "#") {
+		return "", "", false
+	}
+	key = strings.Trim(key, `"'`)
+
+	value = strings.TrimSpace(value)
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return key, value[1 : end+1], true
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return key, strings.TrimSpace(value), true
+}
@@ -0,0 +1,217 @@
+// Package theme reads color schemes written for other terminals, so that
+// screenshots and exports match the colors users see in their terminal.
+//
+// It reads the [colors] section of alacritty.toml, base16 YAML schemes and
+// Windows Terminal JSON schemes:
+//
+//	th, err := theme.Load("gruvbox.toml")
+//	if err != nil {
+//		return err
+//	}
+//	term, err := alacritty.New(80, 24, alacritty.WithPalette(th.Palette))
+package theme
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/example/alacritty-go/color"
+)
+
+// Theme is a color scheme. Colors a scheme does not set keep the values of
+// color.DefaultPalette.
+type Theme struct {
+	// Name is the name the scheme gives itself, or the file name for Load
+	// of a scheme without one
+	Name string
+	// Palette holds the ANSI and default colors, ready for WithPalette
+	Palette color.Palette
+
+	// Dim holds the 8 colors of faint text, nil if the scheme has none
+	Dim *[8]color.RGB
+	// DimForeground and BrightForeground replace the foreground of faint
+	// and bold text, nil if the scheme has none
+	DimForeground    *color.RGB
+	BrightForeground *color.RGB
+
+	// Cursor and CursorText are the colors of the cursor block and the
+	// character under it, nil if the scheme leaves them to the cell
+	Cursor     *color.RGB
+	CursorText *color.RGB
+
+	// SelectionBackground and SelectionText are the colors of selected
+	// text, nil if the scheme leaves them to the cell
+	SelectionBackground *color.RGB
+	SelectionText       *color.RGB
+}
+
+// ansiNames are the names of the 8 normal colors, in the order of
+// Palette.ANSI
+var ansiNames = [8]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// Load reads a scheme from a file, choosing the format by its extension:
+// .toml for alacritty, .yaml or .yml for base16 and .json for Windows
+// Terminal
+func Load(path string) (*Theme, error) {
+	var parse func(io.Reader) (*Theme, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		parse = ParseAlacritty
+	case ".yaml", ".yml":
+		parse = ParseBase16
+	case ".json":
+		parse = ParseWindowsTerminal
+	default:
+		return nil, fmt.Errorf("theme: unknown format of %s", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	th, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s", err, path)
+	}
+	if th.Name == "" {
+		th.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return th, nil
+}
+
+// ParseAlacritty reads the [colors] section of an alacritty.toml file:
+// the primary, normal, bright, dim, cursor and selection tables. Other
+// sections and colors are ignored. Dim colors that the file leaves unset
+// are derived from the normal colors, as alacritty does.
+func ParseAlacritty(r io.Reader) (*Theme, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	th := &Theme{Palette: color.DefaultPalette()}
+	lookup := func(key string) (*color.RGB, error) {
+		value, ok := values["colors."+key]
+		// Cursor and selection colors may name a color of the cell
+		if !ok || value == "CellForeground" || value == "CellBackground" {
+			return nil, nil
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("theme: colors.%s: %w", key, err)
+		}
+		return &c, nil
+	}
+	set := func(key string, dst *color.RGB) error {
+		c, err := lookup(key)
+		if c != nil {
+			*dst = *c
+		}
+		return err
+	}
+
+	if err := set("primary.foreground", &th.Palette.Foreground); err != nil {
+		return nil, err
+	}
+	if err := set("primary.background", &th.Palette.Background); err != nil {
+		return nil, err
+	}
+	for i, name := range ansiNames {
+		if err := set("normal."+name, &th.Palette.ANSI[i]); err != nil {
+			return nil, err
+		}
+		if err := set("bright."+name, &th.Palette.ANSI[8+i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var dim [8]color.RGB
+	for i, name := range ansiNames {
+		c, err := lookup("dim." + name)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			dim[i] = dimColor(th.Palette.ANSI[i])
+			continue
+		}
+		dim[i], th.Dim = *c, &dim
+	}
+
+	optional := []struct {
+		key string
+		dst **color.RGB
+	}{
+		{"primary.dim_foreground", &th.DimForeground},
+		{"primary.bright_foreground", &th.BrightForeground},
+		{"cursor.cursor", &th.Cursor},
+		{"cursor.text", &th.CursorText},
+		{"selection.background", &th.SelectionBackground},
+		{"selection.text", &th.SelectionText},
+	}
+	for _, o := range optional {
+		c, err := lookup(o.key)
+		if err != nil {
+			return nil, err
+		}
+		*o.dst = c
+	}
+
+	return th, nil
+}
+
+// ColorOptions returns options that color text the way alacritty does with
+// the theme: dim text takes the dim colors or is darkened by the default
+// factor, and the default foreground takes the dim and bright foregrounds
+// of the theme
+func (t *Theme) ColorOptions() color.Options {
+	return color.Options{
+		Palette:          &t.Palette,
+		Dim:              t.Dim,
+		DimFactor:        color.DefaultDimFactor,
+		DimForeground:    t.DimForeground,
+		BrightForeground: t.BrightForeground,
+	}
+}
+
+// dimColor derives a dim color from a normal one as alacritty does
+func dimColor(c color.RGB) color.RGB {
+	scale := func(v uint8) uint8 { return uint8(float64(v) * color.DefaultDimFactor) }
+	return color.RGB{R: scale(c.R), G: scale(c.G), B: scale(c.B)}
+}
+
+// parseColor reads a color written as six hex digits, optionally after #
+// or 0x
+func parseColor(s string) (color.RGB, error) {
+	hex := strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(hex, "#"):
+		hex = hex[1:]
+	case strings.HasPrefix(hex, "0x"), strings.HasPrefix(hex, "0X"):
+		hex = hex[2:]
+	}
+	if len(hex) != 6 {
+		return color.RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGB{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// lineAt returns the line of data that offset is on, counted from 1
+func lineAt(data []byte, offset int) int {
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
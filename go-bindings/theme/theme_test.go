@@ -0,0 +1,215 @@
+package theme
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/example/alacritty-go/color"
+)
+
+func rgb(hex uint32) color.RGB {
+	return color.RGB{R: uint8(hex >> 16), G: uint8(hex >> 8), B: uint8(hex)}
+}
+
+func ptr(c color.RGB) *color.RGB {
+	return &c
+}
+
+func TestLoadAlacritty(t *testing.T) {
+	th, err := Load("testdata/gruvbox_dark.toml")
+	if err != nil {
+		t.Fatalf("Failed to load theme: %v", err)
+	}
+
+	expected := &Theme{
+		Name: "gruvbox_dark",
+		Palette: color.Palette{
+			Foreground: rgb(0xebdbb2),
+			Background: rgb(0x282828),
+			ANSI: [16]color.RGB{
+				rgb(0x282828), rgb(0xcc241d), rgb(0x98971a), rgb(0xd79921),
+				rgb(0x458588), rgb(0xb16286), rgb(0x689d6a), rgb(0xa89984),
+				rgb(0x928374), rgb(0xfb4934), rgb(0xb8bb26), rgb(0xfabd2f),
+				rgb(0x83a598), rgb(0xd3869b), rgb(0x8ec07c), rgb(0xebdbb2),
+			},
+		},
+		// Only red is set, the others are derived from the normal colors
+		Dim: &[8]color.RGB{
+			dimColor(rgb(0x282828)), rgb(0x9d0006), dimColor(rgb(0x98971a)), dimColor(rgb(0xd79921)),
+			dimColor(rgb(0x458588)), dimColor(rgb(0xb16286)), dimColor(rgb(0x689d6a)), dimColor(rgb(0xa89984)),
+		},
+		BrightForeground:    ptr(rgb(0xfbf1c7)),
+		Cursor:              ptr(rgb(0xebdbb2)),
+		SelectionBackground: ptr(rgb(0x504945)),
+	}
+	if !reflect.DeepEqual(th, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, th)
+	}
+}
+
+func TestParseAlacrittyDefaults(t *testing.T) {
+	th, err := ParseAlacritty(strings.NewReader(`colors = { primary = { background = "#101010" } }`))
+	if err != nil {
+		t.Fatalf("Failed to parse theme: %v", err)
+	}
+
+	expected := color.DefaultPalette()
+	expected.Background = rgb(0x101010)
+	if th.Palette != expected {
+		t.Errorf("Expected %+v, got %+v", expected, th.Palette)
+	}
+	if th.Dim != nil || th.Cursor != nil {
+		t.Errorf("Expected no dim or cursor colors, got %+v", th)
+	}
+}
+
+func TestParseAlacrittyErrors(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		err   string
+	}{
+		{"[colors.primary]\nforeground = \"#12345\"", "colors.primary.foreground"},
+		{"[colors.normal]\nred = \"#ff0000\" blue = \"#0000ff\"", "line 2: expected end of line"},
+		{"[colors.normal\nred = \"#ff0000\"", "line 1: expected ]"},
+		{"\n\n[colors.bright]\nred = \"#ff0000", "line 4: unterminated string"},
+		{"[colors]\nnormal = { red = \"#ff0000\"", "expected , or }"},
+	} {
+		_, err := ParseAlacritty(strings.NewReader(tc.input))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Expected an error with %q for %q, got %v", tc.err, tc.input, err)
+		}
+	}
+}
+
+func TestParseTOMLStrings(t *testing.T) {
+	values, err := parseTOML([]byte(`
+a = "tab\tquote\"\u00e9"
+b = 'C:\path'
+c = """
+first \
+  second"""
+"quoted.key".d = 1
+[[e]]
+f = "dropped"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	expected := map[string]string{
+		"a": "tab\tquote\"é",
+		"b": `C:\path`,
+		"c": "first second",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %q, got %q", expected, values)
+	}
+}
+
+func TestLoadBase16(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		name string
+		base [16]uint32
+	}{
+		{
+			"testdata/solarized_dark.yaml",
+			"Solarized Dark",
+			[16]uint32{
+				0x002b36, 0x073642, 0x586e75, 0x657b83, 0x839496, 0x93a1a1, 0xeee8d5, 0xfdf6e3,
+				0xdc322f, 0xcb4b16, 0xb58900, 0x859900, 0x2aa198, 0x268bd2, 0x6c71c4, 0xd33682,
+			},
+		},
+		{
+			"testdata/tomorrow_night.yaml",
+			"Tomorrow Night",
+			[16]uint32{
+				0x1d1f21, 0x282a2e, 0x373b41, 0x969896, 0xb4b7b4, 0xc5c8c6, 0xe0e0e0, 0xffffff,
+				0xcc6666, 0xde935f, 0xf0c674, 0xb5bd68, 0x8abeb7, 0x81a2be, 0xb294bb, 0xa3685a,
+			},
+		},
+	} {
+		th, err := Load(tc.path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", tc.path, err)
+		}
+
+		base := func(i int) color.RGB { return rgb(tc.base[i]) }
+		expected := &Theme{
+			Name: tc.name,
+			Palette: color.Palette{
+				Foreground: base(0x05),
+				Background: base(0x00),
+				ANSI: [16]color.RGB{
+					base(0x00), base(0x08), base(0x0B), base(0x0A), base(0x0D), base(0x0E), base(0x0C), base(0x05),
+					base(0x03), base(0x08), base(0x0B), base(0x0A), base(0x0D), base(0x0E), base(0x0C), base(0x07),
+				},
+			},
+			Cursor:              ptr(base(0x05)),
+			CursorText:          ptr(base(0x00)),
+			SelectionBackground: ptr(base(0x02)),
+			SelectionText:       ptr(base(0x05)),
+		}
+		if !reflect.DeepEqual(th, expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.path, expected, th)
+		}
+	}
+}
+
+func TestParseBase16Missing(t *testing.T) {
+	_, err := ParseBase16(strings.NewReader("base00: \"000000\"\nbase01: \"111111\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "missing base02") {
+		t.Errorf("Expected missing base02, got %v", err)
+	}
+}
+
+func TestLoadWindowsTerminal(t *testing.T) {
+	th, err := Load("testdata/campbell.json")
+	if err != nil {
+		t.Fatalf("Failed to load theme: %v", err)
+	}
+
+	expected := &Theme{
+		Name: "Campbell",
+		Palette: color.Palette{
+			Foreground: rgb(0xcccccc),
+			Background: rgb(0x0c0c0c),
+			ANSI: [16]color.RGB{
+				rgb(0x0c0c0c), rgb(0xc50f1f), rgb(0x13a10e), rgb(0xc19c00),
+				rgb(0x0037da), rgb(0x881798), rgb(0x3a96dd), rgb(0xcccccc),
+				rgb(0x767676), rgb(0xe74856), rgb(0x16c60c), rgb(0xf9f1a5),
+				rgb(0x3b78ff), rgb(0xb4009e), rgb(0x61d6d6), rgb(0xf2f2f2),
+			},
+		},
+		Cursor:              ptr(rgb(0xffffff)),
+		SelectionBackground: ptr(rgb(0xffffff)),
+	}
+	if !reflect.DeepEqual(th, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, th)
+	}
+}
+
+func TestLoadUnknownFormat(t *testing.T) {
+	if _, err := Load("testdata/theme.ini"); err == nil || !strings.Contains(err.Error(), "unknown format") {
+		t.Errorf("Expected an unknown format error, got %v", err)
+	}
+}
+
+func TestColorOptions(t *testing.T) {
+	th, err := Load("testdata/gruvbox_dark.toml")
+	if err != nil {
+		t.Fatalf("Failed to load theme: %v", err)
+	}
+
+	opts := th.ColorOptions()
+	if opts.Palette != &th.Palette || opts.DimFactor != color.DefaultDimFactor {
+		t.Errorf("Expected the palette and the default dim factor, got %+v", opts)
+	}
+	if opts.Dim == nil || opts.Dim[1] != rgb(0x9d0006) {
+		t.Errorf("Expected the dim colors of the theme, got %v", opts.Dim)
+	}
+	if opts.BrightForeground != th.BrightForeground || opts.DimForeground != nil {
+		t.Errorf("Expected the bright foreground of the theme, got %v and %v", opts.BrightForeground, opts.DimForeground)
+	}
+}
@@ -0,0 +1,321 @@
+package theme
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlParser reads a TOML document into its string values, keyed by their
+// dotted path. Values of other types are checked and dropped, as are the
+// values of arrays, which a color section does not use.
+type tomlParser struct {
+	data   []byte
+	pos    int
+	values map[string]string
+}
+
+func parseTOML(data []byte) (map[string]string, error) {
+	p := &tomlParser{data: data, values: make(map[string]string)}
+	var table []string
+	// inArray is set in the tables of an array, whose values are dropped
+	// like those of any array
+	inArray := false
+	for {
+		p.skip(true)
+		if p.pos == len(p.data) {
+			return p.values, nil
+		}
+
+		switch {
+		case p.consume("[["):
+			if _, err := p.key(); err != nil {
+				return nil, err
+			}
+			if !p.consume("]]") {
+				return nil, p.errorf("expected ]]")
+			}
+			inArray = true
+		case p.consume("["):
+			path, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume("]") {
+				return nil, p.errorf("expected ]")
+			}
+			table, inArray = path, false
+		default:
+			path, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume("=") {
+				return nil, p.errorf("expected =")
+			}
+			path = append(append([]string(nil), table...), path...)
+			if inArray {
+				path = nil
+			}
+			if err := p.value(path); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skip(false)
+		if p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+			return nil, p.errorf("expected end of line")
+		}
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("theme: line %d: %s", lineAt(p.data, p.pos), fmt.Sprintf(format, args...))
+}
+
+// skip passes over spaces and comments, and over line breaks if newlines
+func (p *tomlParser) skip(newlines bool) {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t':
+		case '\r', '\n':
+			if !newlines {
+				return
+			}
+		case '#':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		default:
+			return
+		}
+		p.pos++
+	}
+}
+
+// consume passes over s after any spaces if it comes next
+func (p *tomlParser) consume(s string) bool {
+	p.skip(false)
+	if !strings.HasPrefix(string(p.data[p.pos:]), s) {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+// key reads a dotted key of bare and quoted parts
+func (p *tomlParser) key() ([]string, error) {
+	var path []string
+	for {
+		p.skip(false)
+		start := p.pos
+		var part string
+		switch {
+		case p.pos < len(p.data) && (p.data[p.pos] == '"' || p.data[p.pos] == '\''):
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			for p.pos < len(p.data) && isBareKey(p.data[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected key")
+			}
+			part = string(p.data[start:p.pos])
+		}
+		path = append(path, part)
+
+		if !p.consume(".") {
+			return path, nil
+		}
+	}
+}
+
+func isBareKey(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b == '-'
+}
+
+// value reads a value, keeping it under path if it is a string. A nil path
+// drops the value.
+func (p *tomlParser) value(path []string) error {
+	p.skip(false)
+	if p.pos == len(p.data) {
+		return p.errorf("expected value")
+	}
+
+	switch p.data[p.pos] {
+	case '"', '\'':
+		s, err := p.str()
+		if err != nil {
+			return err
+		}
+		if path != nil {
+			p.values[strings.Join(path, ".")] = s
+		}
+		return nil
+	case '{':
+		p.pos++
+		if p.consume("}") {
+			return nil
+		}
+		for {
+			key, err := p.key()
+			if err != nil {
+				return err
+			}
+			if !p.consume("=") {
+				return p.errorf("expected =")
+			}
+			if path != nil {
+				key = append(append([]string(nil), path...), key...)
+			} else {
+				key = nil
+			}
+			if err := p.value(key); err != nil {
+				return err
+			}
+			if p.consume("}") {
+				return nil
+			}
+			if !p.consume(",") {
+				return p.errorf("expected , or }")
+			}
+		}
+	case '[':
+		p.pos++
+		for {
+			p.skip(true)
+			if p.consume("]") {
+				return nil
+			}
+			if err := p.value(nil); err != nil {
+				return err
+			}
+			p.skip(true)
+			if p.consume("]") {
+				return nil
+			}
+			if !p.consume(",") {
+				return p.errorf("expected , or ]")
+			}
+		}
+	default:
+		// Numbers, booleans and dates
+		start := p.pos
+		for p.pos < len(p.data) && !strings.ContainsRune(" \t\r\n#,]}", rune(p.data[p.pos])) {
+			p.pos++
+		}
+		if p.pos == start {
+			return p.errorf("expected value")
+		}
+		return nil
+	}
+}
+
+// str reads a basic or literal string, on one line or several
+func (p *tomlParser) str() (string, error) {
+	quote := p.data[p.pos]
+	delim := string(quote)
+	if strings.HasPrefix(string(p.data[p.pos:]), strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+	multiline := len(delim) == 3
+	p.pos += len(delim)
+	// A line break right after the opening quotes is not part of the string
+	if multiline {
+		p.consumeNewline()
+	}
+
+	var b strings.Builder
+	for {
+		if p.pos == len(p.data) {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(string(p.data[p.pos:]), delim) {
+			p.pos += len(delim)
+			return b.String(), nil
+		}
+
+		c := p.data[p.pos]
+		switch {
+		case (c == '\n' || c == '\r') && !multiline:
+			return "", p.errorf("unterminated string")
+		case c == '\\' && quote == '"':
+			if err := p.escape(&b, multiline); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// consumeNewline passes over a line break if one comes next
+func (p *tomlParser) consumeNewline() bool {
+	for _, nl := range []string{"\r\n", "\n"} {
+		if strings.HasPrefix(string(p.data[p.pos:]), nl) {
+			p.pos += len(nl)
+			return true
+		}
+	}
+	return false
+}
+
+// escape reads an escape sequence of a basic string into b
+func (p *tomlParser) escape(b *strings.Builder, multiline bool) error {
+	p.pos++
+	if p.pos == len(p.data) {
+		return p.errorf("unterminated string")
+	}
+
+	c := p.data[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.data) {
+			return p.errorf("invalid escape")
+		}
+		v, err := strconv.ParseUint(string(p.data[p.pos:p.pos+n]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(v)) {
+			return p.errorf("invalid escape")
+		}
+		b.WriteRune(rune(v))
+		p.pos += n
+	default:
+		// A backslash at the end of a line joins it to the next one,
+		// dropping the whitespace between them
+		p.pos--
+		for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+			p.pos++
+		}
+		if !multiline || !p.consumeNewline() {
+			return p.errorf("invalid escape")
+		}
+		for p.pos < len(p.data) && strings.ContainsRune(" \t\r\n", rune(p.data[p.pos])) {
+			p.pos++
+		}
+	}
+	return nil
+}
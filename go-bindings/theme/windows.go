@@ -0,0 +1,102 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/example/alacritty-go/color"
+)
+
+// windowsScheme is a color scheme of Windows Terminal
+type windowsScheme struct {
+	Name                string `json:"name"`
+	Foreground          string `json:"foreground"`
+	Background          string `json:"background"`
+	CursorColor         string `json:"cursorColor"`
+	SelectionBackground string `json:"selectionBackground"`
+
+	Black  string `json:"black"`
+	Red    string `json:"red"`
+	Green  string `json:"green"`
+	Yellow string `json:"yellow"`
+	Blue   string `json:"blue"`
+	Purple string `json:"purple"`
+	Cyan   string `json:"cyan"`
+	White  string `json:"white"`
+
+	BrightBlack  string `json:"brightBlack"`
+	BrightRed    string `json:"brightRed"`
+	BrightGreen  string `json:"brightGreen"`
+	BrightYellow string `json:"brightYellow"`
+	BrightBlue   string `json:"brightBlue"`
+	BrightPurple string `json:"brightPurple"`
+	BrightCyan   string `json:"brightCyan"`
+	BrightWhite  string `json:"brightWhite"`
+}
+
+// ParseWindowsTerminal reads a scheme of the schemes list of Windows
+// Terminal settings, as one JSON object
+func ParseWindowsTerminal(r io.Reader) (*Theme, error) {
+	var scheme windowsScheme
+	if err := json.NewDecoder(r).Decode(&scheme); err != nil {
+		return nil, fmt.Errorf("theme: %w", err)
+	}
+
+	th := &Theme{Name: scheme.Name, Palette: color.DefaultPalette()}
+	colors := []struct {
+		key   string
+		value string
+		dst   *color.RGB
+	}{
+		{"foreground", scheme.Foreground, &th.Palette.Foreground},
+		{"background", scheme.Background, &th.Palette.Background},
+		{"black", scheme.Black, &th.Palette.ANSI[0]},
+		{"red", scheme.Red, &th.Palette.ANSI[1]},
+		{"green", scheme.Green, &th.Palette.ANSI[2]},
+		{"yellow", scheme.Yellow, &th.Palette.ANSI[3]},
+		{"blue", scheme.Blue, &th.Palette.ANSI[4]},
+		{"purple", scheme.Purple, &th.Palette.ANSI[5]},
+		{"cyan", scheme.Cyan, &th.Palette.ANSI[6]},
+		{"white", scheme.White, &th.Palette.ANSI[7]},
+		{"brightBlack", scheme.BrightBlack, &th.Palette.ANSI[8]},
+		{"brightRed", scheme.BrightRed, &th.Palette.ANSI[9]},
+		{"brightGreen", scheme.BrightGreen, &th.Palette.ANSI[10]},
+		{"brightYellow", scheme.BrightYellow, &th.Palette.ANSI[11]},
+		{"brightBlue", scheme.BrightBlue, &th.Palette.ANSI[12]},
+		{"brightPurple", scheme.BrightPurple, &th.Palette.ANSI[13]},
+		{"brightCyan", scheme.BrightCyan, &th.Palette.ANSI[14]},
+		{"brightWhite", scheme.BrightWhite, &th.Palette.ANSI[15]},
+	}
+	for _, c := range colors {
+		if c.value == "" {
+			continue
+		}
+		rgb, err := parseColor(c.value)
+		if err != nil {
+			return nil, fmt.Errorf("theme: %s: %w", c.key, err)
+		}
+		*c.dst = rgb
+	}
+
+	optional := []struct {
+		key   string
+		value string
+		dst   **color.RGB
+	}{
+		{"cursorColor", scheme.CursorColor, &th.Cursor},
+		{"selectionBackground", scheme.SelectionBackground, &th.SelectionBackground},
+	}
+	for _, o := range optional {
+		if o.value == "" {
+			continue
+		}
+		rgb, err := parseColor(o.value)
+		if err != nil {
+			return nil, fmt.Errorf("theme: %s: %w", o.key, err)
+		}
+		*o.dst = &rgb
+	}
+
+	return th, nil
+}
@@ -18,26 +18,207 @@ typedef struct {
     uint8_t bg_g;
     uint8_t bg_b;
     uint16_t flags;    // Cell flags (bold, italic, etc.)
+    uint16_t fg_index; // Palette index of the foreground color
+    uint16_t bg_index; // Palette index of the background color
 } CCell;
 
 // Opaque terminal handle
 typedef struct CTerminal CTerminal;
 
+// C-compatible run of cells linked to the same OSC 8 hyperlink. The
+// strings are not NUL-terminated.
+typedef struct {
+    uint32_t start;       // First column
+    uint32_t end;         // Column after the last
+    const uint8_t* id;
+    size_t id_len;
+    const uint8_t* uri;
+    size_t uri_len;
+} CHyperlinkSpan;
+
+// C-compatible OSC 133 shell integration mark
+typedef struct {
+    int32_t line;         // 0 is the top of the screen, negative lines are the scrollback
+    uint32_t column;
+    uint8_t kind;         // MARK_*
+    uint8_t has_exit_code;
+    int32_t exit_code;    // Exit code of MARK_COMMAND_END, if has_exit_code
+} CMark;
+
+// C-compatible OSC 52 clipboard request
+typedef struct {
+    uint8_t selection;    // Selection parameter, such as 'c' for the clipboard
+    uint8_t load;         // 1 to load the selection, 0 to store data
+    uint8_t st;           // 1 if the request ended with ESC \ rather than BEL
+    const uint8_t* data;  // Base64 text to store, not NUL-terminated
+    size_t data_len;
+} CClipboardRequest;
+
+// Opaque escape sequence parser handle
+typedef struct CParser CParser;
+
+// C-compatible parser event. The pointers point into the parser and stay
+// valid until parser_clear_events.
+typedef struct {
+    uint8_t kind;              // PARSER_EVENT_*
+    uint8_t action;            // Final byte of CSI, DCS and ESC, control of EXECUTE
+    uint8_t ignore;            // 1 if there were more parameters or intermediates than kept
+    uint8_t bell_terminated;   // 1 if the OSC string ended with BEL
+    uint8_t truncated;         // 1 if DCS data was dropped
+    const uint8_t* intermediates;
+    size_t intermediates_len;
+    const uint16_t* params;    // Parameter values, each followed by its subparameters
+    const uint8_t* param_lens; // Number of values of each parameter
+    size_t params_len;         // Number of parameters
+    const uint8_t* data;       // PRINT text, OSC parameters joined by ';' or DCS data
+    size_t data_len;
+} CParserEvent;
+
+// C-compatible color
+typedef struct {
+    uint8_t r;
+    uint8_t g;
+    uint8_t b;
+} CRgb;
+
+// Options of terminal_new_with_options, filled with the defaults by
+// terminal_default_options
+typedef struct {
+    uint32_t scrollback_lines;
+    uint32_t max_columns;     // Largest accepted size, 0 for no limit
+    uint32_t max_lines;
+    uint8_t cursor_shape;     // CURSOR_SHAPE_*
+    uint8_t cursor_blinking;
+    uint8_t kitty_keyboard;
+    uint8_t osc52;            // OSC52_*
+    uint32_t clipboard_limit; // Largest text in bytes an OSC 52 store may hold
+    const char* semantic_escape_chars; // UTF-8, NULL for the default
+    CRgb palette[18];         // ANSI colors 0-15, foreground, background
+} CTerminalOptions;
+
 // Cell flag constants
 #define CELL_FLAG_BOLD      (1 << 0)
 #define CELL_FLAG_ITALIC    (1 << 1)
 #define CELL_FLAG_UNDERLINE (1 << 2)
 #define CELL_FLAG_INVERSE   (1 << 3)
+#define CELL_FLAG_STRIKEOUT (1 << 4)
+#define CELL_FLAG_WIDE_CHAR (1 << 5)
+#define CELL_FLAG_WIDE_CHAR_SPACER (1 << 6)
+#define CELL_FLAG_WRAPLINE  (1 << 7)
+#define CELL_FLAG_DIM       (1 << 8)
+#define CELL_FLAG_HIDDEN    (1 << 9)
+#define CELL_FLAG_HYPERLINK (1 << 10)
+
+// Palette indices of the default colors and of direct (truecolor) colors
+#define COLOR_INDEX_FOREGROUND 256
+#define COLOR_INDEX_BACKGROUND 257
+#define COLOR_INDEX_CURSOR     258
+#define COLOR_INDEX_DIRECT     0xffff
+
+// Number of colors in the table of terminal_get_colors
+#define COLOR_COUNT 269
+
+// Terminal mode constants
+#define MODE_SHOW_CURSOR            (1 << 0)
+#define MODE_APP_CURSOR             (1 << 1)
+#define MODE_APP_KEYPAD             (1 << 2)
+#define MODE_MOUSE_REPORT_CLICK     (1 << 3)
+#define MODE_BRACKETED_PASTE        (1 << 4)
+#define MODE_SGR_MOUSE              (1 << 5)
+#define MODE_MOUSE_MOTION           (1 << 6)
+#define MODE_LINE_WRAP              (1 << 7)
+#define MODE_LINE_FEED_NEW_LINE     (1 << 8)
+#define MODE_ORIGIN                 (1 << 9)
+#define MODE_INSERT                 (1 << 10)
+#define MODE_FOCUS_IN_OUT           (1 << 11)
+#define MODE_ALT_SCREEN             (1 << 12)
+#define MODE_MOUSE_DRAG             (1 << 13)
+#define MODE_UTF8_MOUSE             (1 << 14)
+#define MODE_ALTERNATE_SCROLL       (1 << 15)
+#define MODE_URGENCY_HINTS          (1 << 16)
+#define MODE_DISAMBIGUATE_ESC_CODES (1 << 17)
+
+// Cursor shapes
+#define CURSOR_SHAPE_BLOCK        0
+#define CURSOR_SHAPE_UNDERLINE    1
+#define CURSOR_SHAPE_BEAM         2
+#define CURSOR_SHAPE_HOLLOW_BLOCK 3
+#define CURSOR_SHAPE_HIDDEN       4
+
+// OSC 52 clipboard policies
+#define OSC52_DISABLED   0
+#define OSC52_ONLY_COPY  1
+#define OSC52_ONLY_PASTE 2
+#define OSC52_COPY_PASTE 3
+
+// Kinds of shell integration marks, OSC 133 A to D
+#define MARK_PROMPT_START  0
+#define MARK_COMMAND_START 1
+#define MARK_OUTPUT_START  2
+#define MARK_COMMAND_END   3
+
+// Kinds of parser events
+#define PARSER_EVENT_PRINT   0
+#define PARSER_EVENT_EXECUTE 1
+#define PARSER_EVENT_CSI     2
+#define PARSER_EVENT_OSC     3
+#define PARSER_EVENT_DCS     4
+#define PARSER_EVENT_ESC     5
+
+// Flags for terminal_save_state
+#define SAVE_STATE_NO_HISTORY 1
+
+// Error codes returned by the functions, detailed by terminal_last_error on
+// the thread of the failing call
+#define ERROR_NULL_POINTER       (-1)
+#define ERROR_OUT_OF_BOUNDS      (-2)
+#define ERROR_INVALID_SIZE       (-3)
+#define ERROR_INVALID_STATE      (-4)
+#define ERROR_UNKNOWN_CHECKPOINT (-5)
+#define ERROR_POISONED           (-6)
 
 // Function declarations
+int terminal_default_options(CTerminalOptions* options);
 CTerminal* terminal_new(uint32_t cols, uint32_t rows);
+CTerminal* terminal_new_with_options(uint32_t cols, uint32_t rows, const CTerminalOptions* options);
 void terminal_free(CTerminal* terminal);
 int terminal_process_bytes(CTerminal* terminal, const uint8_t* input, size_t input_len);
+int terminal_clear_history(CTerminal* terminal);
 CCell terminal_get_cell(const CTerminal* terminal, uint32_t x, uint32_t y);
 int terminal_get_line(const CTerminal* terminal, uint32_t y, CCell* output_cells, size_t max_cells);
+int terminal_get_history_size(const CTerminal* terminal);
+int terminal_get_history_line(const CTerminal* terminal, uint32_t offset, CCell* output_cells, size_t max_cells);
+int terminal_get_hyperlinks(const CTerminal* terminal, int32_t line, CHyperlinkSpan* spans, size_t max_spans);
+int terminal_get_marks(const CTerminal* terminal, CMark* marks, size_t max_marks);
 int terminal_resize(CTerminal* terminal, uint32_t cols, uint32_t rows);
 int terminal_get_size(const CTerminal* terminal, uint32_t* cols, uint32_t* rows);
 int terminal_get_cursor(const CTerminal* terminal, uint32_t* x, uint32_t* y);
+int terminal_get_wrap_pending(const CTerminal* terminal, uint8_t* pending);
+int terminal_get_scroll_region(const CTerminal* terminal, uint32_t* top, uint32_t* bottom);
+int terminal_begin_primary(CTerminal* terminal);
+int terminal_end_primary(CTerminal* terminal);
+int terminal_get_pen(const CTerminal* terminal, CCell* pen);
+int terminal_get_title(const CTerminal* terminal, uint8_t* buf, size_t max_len);
+int terminal_get_working_directory(const CTerminal* terminal, uint8_t* buf, size_t max_len);
+int terminal_get_colors(const CTerminal* terminal, CRgb* colors, size_t max_colors);
+int terminal_take_replies(CTerminal* terminal, uint8_t* buf, size_t max_len);
+int terminal_get_clipboard_requests(const CTerminal* terminal, CClipboardRequest* requests, size_t max_requests);
+int terminal_clear_clipboard_requests(CTerminal* terminal);
+int terminal_get_mode(const CTerminal* terminal, uint32_t* mode);
+int terminal_save_state(CTerminal* terminal, uint32_t flags, uint8_t** data, size_t* len);
+void terminal_free_state(uint8_t* data, size_t len);
+int terminal_restore_state(CTerminal* terminal, const uint8_t* data, size_t len);
+int terminal_checkpoint(CTerminal* terminal, uint64_t* id);
+int terminal_rollback(CTerminal* terminal, uint64_t id);
+int terminal_release_checkpoint(CTerminal* terminal, uint64_t id);
+int terminal_last_error(uint8_t* buf, size_t max_len);
+// Only in libraries built with the test-panic feature
+int terminal_test_panic(CTerminal* terminal);
+CParser* parser_new(void);
+void parser_free(CParser* parser);
+int parser_advance(CParser* parser, const uint8_t* input, size_t input_len);
+int parser_get_events(const CParser* parser, CParserEvent* events, size_t max_events);
+int parser_clear_events(CParser* parser);
 
 #ifdef __cplusplus
 }
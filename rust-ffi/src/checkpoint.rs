@@ -0,0 +1,215 @@
+//! Checkpoints of the terminal state, copied when the terminal changes.
+//!
+//! A checkpoint copies the screens but shares the history of the primary
+//! screen with the terminal: lines in the history do not change until they
+//! leave it, so a checkpoint records how many lines the history had and
+//! copies the oldest of them only as the terminal drops them. Resizing,
+//! clearing the history and resetting rewrite the history, so the lines
+//! still shared are copied before.
+
+use std::collections::HashMap;
+use std::mem;
+
+use alacritty_terminal::event::EventListener;
+use alacritty_terminal::grid::{Dimensions, Grid, Row, Scroll};
+use alacritty_terminal::index::Line;
+use alacritty_terminal::term::cell::Cell;
+use alacritty_terminal::Term;
+
+use crate::state::{self, TerminalState};
+use crate::CTerminal;
+
+/// Most input processed at once while checkpoints share the history, which
+/// bounds the lines it may push into the history before they are copied
+pub(crate) const CHUNK: usize = 4096;
+
+/// State of the checkpoints taken without changes in between
+struct Snapshot {
+    /// State without the history of the primary screen
+    state: TerminalState,
+    /// Lines the history had
+    history: usize,
+    /// Oldest of those lines, copied before they left the history
+    kept: Vec<Row<Cell>>,
+}
+
+impl Snapshot {
+    /// Number of lines that are still the oldest lines of the history
+    fn shared(&self) -> usize {
+        self.history - self.kept.len()
+    }
+
+    /// Copy up to count of the shared lines of grid, oldest first
+    fn keep(&mut self, grid: &Grid<Cell>, count: usize) {
+        let history = grid.history_size() as i32;
+        let count = count.min(self.shared()) as i32;
+        self.kept.extend((0..count).map(|i| grid[Line(i - history)].clone()));
+    }
+}
+
+#[derive(Default)]
+pub(crate) struct Checkpoints {
+    next_id: u64,
+    /// Checkpoints taken since the terminal last changed, which all share
+    /// the current state
+    pending: Vec<u64>,
+    /// Snapshot of each saved checkpoint
+    saved: HashMap<u64, u64>,
+    /// Snapshots by the first checkpoint they were taken for
+    snapshots: HashMap<u64, Snapshot>,
+    /// The history may grow beyond the configured size until it is trimmed
+    raised: bool,
+}
+
+impl Checkpoints {
+    /// Register a checkpoint of the current state without copying it
+    pub fn create(&mut self) -> u64 {
+        self.next_id += 1;
+        self.pending.push(self.next_id);
+        self.next_id
+    }
+
+    pub fn release(&mut self, id: u64) -> bool {
+        if let Some(i) = self.pending.iter().position(|&p| p == id) {
+            self.pending.remove(i);
+            return true;
+        }
+        let Some(snapshot) = self.saved.remove(&id) else {
+            return false;
+        };
+        if !self.saved.values().any(|&s| s == snapshot) {
+            self.snapshots.remove(&snapshot);
+        }
+        true
+    }
+
+    /// Whether a snapshot shares lines with the history
+    pub fn sharing(&self) -> bool {
+        self.snapshots.values().any(|s| s.shared() > 0)
+    }
+
+    /// Copy the lines snapshots share, before the history is rewritten
+    pub fn detach<L: EventListener>(&mut self, term: &mut Term<L>) {
+        if !self.sharing() {
+            return;
+        }
+        state::with_primary(term, |grid| {
+            for snapshot in self.snapshots.values_mut() {
+                snapshot.keep(grid, usize::MAX);
+            }
+        });
+    }
+
+    /// Drop the lines beyond max from the history, copying those that
+    /// snapshots share first. While they share lines, the history may grow
+    /// by as many lines as CHUNK bytes of input can push, so that the
+    /// terminal does not drop any of them itself.
+    pub fn trim<L: EventListener>(&mut self, term: &mut Term<L>, max: usize) {
+        let room = if self.sharing() { CHUNK * term.screen_lines() } else { 0 };
+        if room == 0 && !self.raised {
+            return;
+        }
+
+        state::with_primary(term, |grid| {
+            let excess = grid.history_size().saturating_sub(max);
+            for snapshot in self.snapshots.values_mut() {
+                snapshot.keep(grid, excess);
+            }
+            grid.update_history(max);
+            grid.update_history(max + room);
+        });
+        self.raised = room > 0;
+    }
+}
+
+/// Copy the state for the pending checkpoints. Must be called before
+/// anything changes the terminal. The history is shared, not copied.
+pub(crate) fn before_change(terminal: &mut CTerminal) {
+    let Some(&first) = terminal.checkpoints.pending.first() else {
+        return;
+    };
+
+    let state = state::capture(terminal, false);
+    let history = state::with_primary(&mut terminal.term, |grid| grid.history_size());
+    let checkpoints = &mut terminal.checkpoints;
+    for id in checkpoints.pending.drain(..) {
+        checkpoints.saved.insert(id, first);
+    }
+    checkpoints.snapshots.insert(first, Snapshot { state, history, kept: Vec::new() });
+    checkpoints.trim(&mut terminal.term, terminal.config.scrolling_history);
+}
+
+/// Return to the state of a checkpoint, which stays valid. The shared lines
+/// of the history are moved into place rather than copied, and the restored
+/// terminal shares its whole history with the checkpoint again.
+pub(crate) fn rollback(terminal: &mut CTerminal, id: u64) -> bool {
+    // Nothing changed since the checkpoint was taken
+    if terminal.checkpoints.pending.contains(&id) {
+        return true;
+    }
+    let Some(&snapshot_id) = terminal.checkpoints.saved.get(&id) else {
+        return false;
+    };
+
+    before_change(terminal);
+    let Some(mut snapshot) = terminal.checkpoints.snapshots.remove(&snapshot_id) else {
+        return false;
+    };
+    if !state::fits(terminal, &snapshot.state) {
+        terminal.checkpoints.snapshots.insert(snapshot_id, snapshot);
+        return false;
+    }
+    // The history is rearranged below, so the other snapshots copy it first
+    terminal.checkpoints.detach(&mut terminal.term);
+
+    let max = terminal.config.scrolling_history;
+    let live = state::with_primary(&mut terminal.term, |grid| mem::replace(grid, Grid::new(1, 1, 0)));
+    let primary = restore_history(live, &mut snapshot, max);
+    state::apply(terminal, &snapshot.state, primary);
+
+    let checkpoints = &mut terminal.checkpoints;
+    checkpoints.snapshots.insert(snapshot_id, snapshot);
+    checkpoints.trim(&mut terminal.term, max);
+    true
+}
+
+/// Build the primary grid of snapshot from the live one, taking the lines
+/// copied from the snapshot
+fn restore_history(mut live: Grid<Cell>, snapshot: &mut Snapshot, max: usize) -> Grid<Cell> {
+    let kept = mem::take(&mut snapshot.kept);
+    let shared = snapshot.history - kept.len();
+    let history = live.history_size();
+    let pushed = history - shared;
+    let (columns, lines) = snapshot.state.size();
+
+    if live.columns() != columns || live.screen_lines() != lines || pushed < kept.len() {
+        // Not enough lines to rearrange, which only happens once nothing
+        // is shared anymore
+        let shared_rows = (0..shared as i32)
+            .map(|i| mem::replace(&mut live[Line(i - history as i32)], Row::new(1)))
+            .collect::<Vec<_>>();
+        return snapshot.state.primary_grid(kept.into_iter().chain(shared_rows), max);
+    }
+
+    // The lines pushed since the checkpoint lie between the shared lines
+    // and the screen. Moving the shared lines over them leaves the pushed
+    // lines at the top, where the copied lines take the place of the newest
+    // and the history drops the rest.
+    let line = |offset: usize| Line(offset as i32 - history as i32);
+    let mut spare = Row::new(1);
+    for i in (0..shared).rev() {
+        mem::swap(&mut live[line(i)], &mut spare);
+        mem::swap(&mut live[line(i + pushed)], &mut spare);
+        mem::swap(&mut live[line(i)], &mut spare);
+    }
+    let start = pushed - kept.len();
+    for (i, row) in kept.into_iter().enumerate() {
+        live[line(start + i)] = row;
+    }
+    live.update_history(snapshot.history);
+    live.update_history(max);
+
+    snapshot.state.primary_screen(&mut live);
+    live.scroll_display(Scroll::Bottom);
+    live
+}
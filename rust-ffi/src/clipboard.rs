@@ -0,0 +1,55 @@
+//! Clipboard requests sent with OSC 52, which are queued until the caller
+//! takes them instead of going to alacritty's event listener.
+
+use alacritty_terminal::term::Osc52;
+
+/// Most requests kept until the caller takes them
+const MAX_PENDING: usize = 64;
+
+/// Request to store or load a selection
+#[derive(Debug, Clone)]
+pub(crate) struct ClipboardRequest {
+    /// Selection parameter, such as b'c' for the clipboard
+    pub selection: u8,
+    /// Base64 text to store, or None to load the selection
+    pub data: Option<Vec<u8>>,
+    /// The request ended with ESC \ rather than BEL, which the answer to a
+    /// load repeats
+    pub st: bool,
+}
+
+/// Requests allowed by the OSC 52 policy, oldest first
+#[derive(Debug)]
+pub(crate) struct ClipboardRequests {
+    pub policy: Osc52,
+    /// Size in bytes of the largest text a store may hold
+    pub limit: usize,
+    pub pending: Vec<ClipboardRequest>,
+}
+
+impl ClipboardRequests {
+    pub fn new(policy: Osc52, limit: usize) -> Self {
+        ClipboardRequests { policy, limit, pending: Vec::new() }
+    }
+
+    /// Queue a store unless its text is over the limit, which is known from
+    /// the length of the base64 without decoding it
+    pub fn store(&mut self, selection: u8, base64: &[u8]) {
+        let max_len = self.limit.div_ceil(3).saturating_mul(4);
+        if matches!(self.policy, Osc52::OnlyCopy | Osc52::CopyPaste) && base64.len() <= max_len {
+            self.push(ClipboardRequest { selection, data: Some(base64.to_vec()), st: false });
+        }
+    }
+
+    pub fn load(&mut self, selection: u8, terminator: &str) {
+        if matches!(self.policy, Osc52::OnlyPaste | Osc52::CopyPaste) {
+            self.push(ClipboardRequest { selection, data: None, st: terminator != "\x07" });
+        }
+    }
+
+    fn push(&mut self, request: ClipboardRequest) {
+        if self.pending.len() < MAX_PENDING {
+            self.pending.push(request);
+        }
+    }
+}
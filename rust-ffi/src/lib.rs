@@ -1,10 +1,28 @@
-use std::os::raw::{c_int, c_uint};
+use std::cell::RefCell;
+use std::ffi::CStr;
+use std::os::raw::{c_char, c_int, c_uint};
+use std::panic::{self, AssertUnwindSafe};
 use std::slice;
+use std::sync::OnceLock;
+
+mod checkpoint;
+mod clipboard;
+mod parser;
+mod shell;
+mod state;
+
+use checkpoint::Checkpoints;
+use clipboard::ClipboardRequests;
+use parser::Events;
+use shell::ReportScanner;
+use state::{SequenceTail, TrackedState, Tracker};
 
 use alacritty_terminal::{Term, event::VoidListener, grid::Dimensions};
-use alacritty_terminal::term::{Config, cell::{Cell, Flags}};
-use alacritty_terminal::vte::ansi::{Color, NamedColor, Rgb, Processor};
+use alacritty_terminal::term::{Config, Osc52, TermMode, cell::{Cell, Flags}};
+use alacritty_terminal::term::color::{Colors, COUNT};
+use alacritty_terminal::vte::ansi::{ClearMode, Color, CursorStyle, Handler, NamedColor, Rgb, Processor};
 use alacritty_terminal::index::{Point, Line, Column};
+use alacritty_terminal::vte::Parser;
 
 /// C-compatible cell structure
 #[repr(C)]
@@ -18,6 +36,8 @@ pub struct CCell {
     pub bg_g: u8,
     pub bg_b: u8,
     pub flags: u16,       // Cell flags (bold, italic, etc.)
+    pub fg_index: u16,    // Palette index of the foreground color
+    pub bg_index: u16,    // Palette index of the background color
 }
 
 impl Default for CCell {
@@ -27,10 +47,24 @@ impl Default for CCell {
             fg_r: 255, fg_g: 255, fg_b: 255,  // White foreground
             bg_r: 0, bg_g: 0, bg_b: 0,        // Black background
             flags: 0,
+            fg_index: NamedColor::Foreground as u16,
+            bg_index: NamedColor::Background as u16,
         }
     }
 }
 
+/// Palette index reported for direct (truecolor) colors
+const COLOR_INDEX_DIRECT: u16 = 0xffff;
+
+/// Palette index of a cell color, matching alacritty's color table layout
+fn color_index(color: &Color) -> u16 {
+    match color {
+        Color::Named(named) => *named as u16,
+        Color::Indexed(idx) => *idx as u16,
+        Color::Spec(_) => COLOR_INDEX_DIRECT,
+    }
+}
+
 /// C-compatible terminal size structure
 #[repr(C)]
 #[derive(Debug, Clone, Copy)]
@@ -58,90 +92,258 @@ pub struct CTerminal {
     term: Term<VoidListener>,
     parser: Processor,
     size: CTermSize,
+    state: TrackedState,
+    tail: SequenceTail,
+    scanner: ReportScanner,
+    checkpoints: Checkpoints,
+    clipboard: ClipboardRequests,
+    /// Answers to the application, taken with terminal_take_replies
+    replies: Vec<u8>,
+    config: Config,
+    palette: Palette,
+    /// Largest accepted size, 0 for no limit
+    max_size: CTermSize,
+    /// Alternate screen put aside by terminal_begin_primary
+    alternate: Option<state::Alternate>,
+    /// Message of the panic that left the terminal in an unknown state.
+    /// Once set, every function but terminal_free fails with ERROR_POISONED.
+    poisoned: OnceLock<String>,
 }
 
-/// Convert Alacritty Cell to CCell
-fn cell_to_ccell(cell: &Cell) -> CCell {
-    let c = cell.c as u32;
-    
-    // Extract colors
-    let (fg_r, fg_g, fg_b) = match cell.fg {
-        Color::Named(NamedColor::Foreground) => (255, 255, 255),
-        Color::Named(NamedColor::Background) => (0, 0, 0),
-        Color::Named(NamedColor::Black) => (0, 0, 0),
-        Color::Named(NamedColor::Red) => (255, 0, 0),
-        Color::Named(NamedColor::Green) => (0, 255, 0),
-        Color::Named(NamedColor::Yellow) => (255, 255, 0),
-        Color::Named(NamedColor::Blue) => (0, 0, 255),
-        Color::Named(NamedColor::Magenta) => (255, 0, 255),
-        Color::Named(NamedColor::Cyan) => (0, 255, 255),
-        Color::Named(NamedColor::White) => (255, 255, 255),
-        Color::Named(NamedColor::BrightBlack) => (128, 128, 128),
-        Color::Named(NamedColor::BrightRed) => (255, 128, 128),
-        Color::Named(NamedColor::BrightGreen) => (128, 255, 128),
-        Color::Named(NamedColor::BrightYellow) => (255, 255, 128),
-        Color::Named(NamedColor::BrightBlue) => (128, 128, 255),
-        Color::Named(NamedColor::BrightMagenta) => (255, 128, 255),
-        Color::Named(NamedColor::BrightCyan) => (128, 255, 255),
-        Color::Named(NamedColor::BrightWhite) => (255, 255, 255),
-        Color::Spec(Rgb { r, g, b }) => (r, g, b),
-        Color::Indexed(idx) => {
-            // Simple mapping for indexed colors
-            match idx {
-                0..=7 => {
-                    let colors = [(0,0,0), (255,0,0), (0,255,0), (255,255,0), 
-                                 (0,0,255), (255,0,255), (0,255,255), (255,255,255)];
-                    colors[idx as usize]
-                }
-                8..=15 => {
-                    let colors = [(128,128,128), (255,128,128), (128,255,128), (255,255,128),
-                                 (128,128,255), (255,128,255), (128,255,255), (255,255,255)];
-                    colors[(idx - 8) as usize]
-                }
-                _ => (128, 128, 128), // Default gray for other indexed colors
+thread_local! {
+    /// Detail of the last failure on this thread, read with
+    /// terminal_last_error. Callers failing concurrently on other threads
+    /// cannot replace it before it is read.
+    static LAST_ERROR: RefCell<String> = RefCell::new(String::new());
+}
+
+/// Error codes returned by the functions
+const ERROR_NULL_POINTER: c_int = -1;
+const ERROR_OUT_OF_BOUNDS: c_int = -2;
+const ERROR_INVALID_SIZE: c_int = -3;
+const ERROR_INVALID_STATE: c_int = -4;
+const ERROR_UNKNOWN_CHECKPOINT: c_int = -5;
+const ERROR_POISONED: c_int = -6;
+
+impl CTerminal {
+    /// Colors in use, for resolving cell colors
+    fn colors(&self) -> ColorTable {
+        color_table(self.term.colors(), &self.palette)
+    }
+
+}
+
+/// Record the detail of a failure for the calling thread and return its
+/// error code
+fn fail(code: c_int, detail: String) -> c_int {
+    LAST_ERROR.with(|last_error| *last_error.borrow_mut() = detail);
+    code
+}
+
+/// Run the body of a function on terminal, returning failed instead if the
+/// terminal is poisoned or the body panics, which poisons the terminal.
+/// Panics only unwind to here when built with panic = "unwind", as the
+/// release-unwind profile the Makefile builds by default; the release
+/// profile aborts the process instead.
+fn guard<T>(terminal: *const CTerminal, failed: T, f: impl FnOnce() -> T) -> T {
+    if let Some(message) = unsafe { terminal.as_ref() }.and_then(|t| t.poisoned.get()) {
+        fail(ERROR_POISONED, format!("panic: {}", message));
+        return failed;
+    }
+
+    match panic::catch_unwind(AssertUnwindSafe(f)) {
+        Ok(result) => result,
+        Err(payload) => {
+            if !terminal.is_null() {
+                let terminal = unsafe { &*terminal };
+                let message = panic_message(&*payload);
+                let _ = terminal.poisoned.set(message.to_string());
+                fail(ERROR_POISONED, format!("panic: {}", message));
             }
-        }
-        _ => (255, 255, 255), // Default white for unhandled colors
+            failed
+        },
+    }
+}
+
+/// Extract the message of a panic payload
+fn panic_message(payload: &(dyn std::any::Any + Send)) -> &str {
+    if let Some(message) = payload.downcast_ref::<&str>() {
+        message
+    } else if let Some(message) = payload.downcast_ref::<String>() {
+        message
+    } else {
+        "unknown panic"
+    }
+}
+
+/// Copy a string into a caller buffer, truncated to max_len bytes, and
+/// return its full length
+unsafe fn copy_str(s: &str, buf: *mut u8, max_len: usize) -> c_int {
+    let len = std::cmp::min(s.len(), max_len);
+    if len > 0 {
+        std::ptr::copy_nonoverlapping(s.as_ptr(), buf, len);
+    }
+    s.len() as c_int
+}
+
+/// C-compatible color
+#[repr(C)]
+#[derive(Debug, Clone, Copy)]
+pub struct CRgb {
+    pub r: u8,
+    pub g: u8,
+    pub b: u8,
+}
+
+/// Options of terminal_new_with_options
+#[repr(C)]
+#[derive(Debug, Clone, Copy)]
+pub struct CTerminalOptions {
+    pub scrollback_lines: c_uint,
+    pub max_columns: c_uint,   // Largest accepted size, 0 for no limit
+    pub max_lines: c_uint,
+    pub cursor_shape: u8,      // Index in CURSOR_SHAPES
+    pub cursor_blinking: u8,
+    pub kitty_keyboard: u8,
+    pub osc52: u8,             // Index in OSC52_POLICIES
+    pub clipboard_limit: c_uint, // Largest text in bytes an OSC 52 store may hold
+    pub semantic_escape_chars: *const c_char, // NULL for the default
+    pub palette: [CRgb; 18],   // ANSI colors, foreground, background
+}
+
+/// Largest size accepted by default, guarding against absurd allocations
+const DEFAULT_MAX_SIZE: c_uint = 4096;
+
+/// Largest text of an OSC 52 store by default
+const DEFAULT_CLIPBOARD_LIMIT: c_uint = 1 << 20;
+
+/// OSC 52 policies in the order of the OSC52_* constants
+const OSC52_POLICIES: [Osc52; 4] = [Osc52::Disabled, Osc52::OnlyCopy, Osc52::OnlyPaste, Osc52::CopyPaste];
+
+/// Build the alacritty configuration described by options
+unsafe fn config_from_options(options: &CTerminalOptions) -> Config {
+    let mut config = Config::default();
+    config.scrolling_history = options.scrollback_lines as usize;
+    config.default_cursor_style = CursorStyle {
+        shape: state::CURSOR_SHAPES[options.cursor_shape as usize % state::CURSOR_SHAPES.len()],
+        blinking: options.cursor_blinking != 0,
     };
+    config.kitty_keyboard = options.kitty_keyboard != 0;
+    config.osc52 = OSC52_POLICIES[options.osc52 as usize % OSC52_POLICIES.len()];
+    if !options.semantic_escape_chars.is_null() {
+        config.semantic_escape_chars =
+            CStr::from_ptr(options.semantic_escape_chars).to_string_lossy().into_owned();
+    }
+    config
+}
 
-    let (bg_r, bg_g, bg_b) = match cell.bg {
-        Color::Named(NamedColor::Background) => (0, 0, 0),
-        Color::Named(NamedColor::Foreground) => (255, 255, 255),
-        Color::Named(NamedColor::Black) => (0, 0, 0),
-        Color::Named(NamedColor::Red) => (255, 0, 0),
-        Color::Named(NamedColor::Green) => (0, 255, 0),
-        Color::Named(NamedColor::Yellow) => (255, 255, 0),
-        Color::Named(NamedColor::Blue) => (0, 0, 255),
-        Color::Named(NamedColor::Magenta) => (255, 0, 255),
-        Color::Named(NamedColor::Cyan) => (0, 255, 255),
-        Color::Named(NamedColor::White) => (255, 255, 255),
-        Color::Named(NamedColor::BrightBlack) => (128, 128, 128),
-        Color::Named(NamedColor::BrightRed) => (255, 128, 128),
-        Color::Named(NamedColor::BrightGreen) => (128, 255, 128),
-        Color::Named(NamedColor::BrightYellow) => (255, 255, 128),
-        Color::Named(NamedColor::BrightBlue) => (128, 128, 255),
-        Color::Named(NamedColor::BrightMagenta) => (255, 128, 255),
-        Color::Named(NamedColor::BrightCyan) => (128, 255, 255),
-        Color::Named(NamedColor::BrightWhite) => (255, 255, 255),
-        Color::Spec(Rgb { r, g, b }) => (r, g, b),
-        Color::Indexed(idx) => {
-            match idx {
-                0..=7 => {
-                    let colors = [(0,0,0), (255,0,0), (0,255,0), (255,255,0), 
-                                 (0,0,255), (255,0,255), (0,255,255), (255,255,255)];
-                    colors[idx as usize]
-                }
-                8..=15 => {
-                    let colors = [(128,128,128), (255,128,128), (128,255,128), (255,255,128),
-                                 (128,128,255), (255,128,255), (128,255,255), (255,255,255)];
-                    colors[(idx - 8) as usize]
-                }
-                _ => (0, 0, 0), // Default black for other indexed colors
-            }
-        }
-        _ => (0, 0, 0), // Default black for unhandled colors
+/// Create the alacritty terminal of a CTerminal
+fn new_term(size: &CTermSize, config: &Config) -> Term<VoidListener> {
+    Term::new(config.clone(), size, VoidListener)
+}
+
+/// Describe why a size is not accepted, if it is not
+fn check_size(max_size: &CTermSize, cols: c_uint, rows: c_uint) -> Option<String> {
+    if cols == 0 || rows == 0 {
+        return Some(format!("invalid size {}x{}", cols, rows));
+    }
+    let too_wide = max_size.columns != 0 && cols > max_size.columns;
+    let too_high = max_size.screen_lines != 0 && rows > max_size.screen_lines;
+    if too_wide || too_high {
+        return Some(format!(
+            "size {}x{} exceeds the maximum of {}x{}",
+            cols, rows, max_size.columns, max_size.screen_lines
+        ));
+    }
+    None
+}
+
+/// Colors used to resolve named and indexed cell colors: the 16 ANSI colors
+/// followed by the default foreground and background
+pub(crate) type Palette = [Rgb; 18];
+
+const DEFAULT_PALETTE: Palette = [
+    Rgb { r: 0, g: 0, b: 0 },
+    Rgb { r: 255, g: 0, b: 0 },
+    Rgb { r: 0, g: 255, b: 0 },
+    Rgb { r: 255, g: 255, b: 0 },
+    Rgb { r: 0, g: 0, b: 255 },
+    Rgb { r: 255, g: 0, b: 255 },
+    Rgb { r: 0, g: 255, b: 255 },
+    Rgb { r: 255, g: 255, b: 255 },
+    Rgb { r: 128, g: 128, b: 128 },
+    Rgb { r: 255, g: 128, b: 128 },
+    Rgb { r: 128, g: 255, b: 128 },
+    Rgb { r: 255, g: 255, b: 128 },
+    Rgb { r: 128, g: 128, b: 255 },
+    Rgb { r: 255, g: 128, b: 255 },
+    Rgb { r: 128, g: 255, b: 255 },
+    Rgb { r: 255, g: 255, b: 255 },
+    Rgb { r: 255, g: 255, b: 255 }, // Foreground
+    Rgb { r: 0, g: 0, b: 0 },       // Background
+];
+
+/// Live colors in alacritty's layout: the 256 indexed colors followed by
+/// the named colors from Foreground on
+pub(crate) type ColorTable = [Rgb; COUNT];
+
+/// Factor of the dim colors, matching alacritty
+const DIM_FACTOR: f32 = 0.66;
+
+/// Levels of the red, green and blue components in the 6x6x6 color cube
+const CUBE_LEVELS: [u8; 6] = [0, 95, 135, 175, 215, 255];
+
+/// Build the colors in use: those changed with OSC 4, 10, 11 and 12, and
+/// defaults from the palette and the xterm 256 color table for the rest
+pub(crate) fn color_table(colors: &Colors, palette: &Palette) -> ColorTable {
+    let dim = |c: Rgb| Rgb {
+        r: (c.r as f32 * DIM_FACTOR) as u8,
+        g: (c.g as f32 * DIM_FACTOR) as u8,
+        b: (c.b as f32 * DIM_FACTOR) as u8,
     };
 
+    let mut table = [Rgb { r: 0, g: 0, b: 0 }; COUNT];
+    table[..16].copy_from_slice(&palette[..16]);
+    for i in 0..216 {
+        let level = |n: usize| CUBE_LEVELS[n % 6];
+        table[16 + i] = Rgb { r: level(i / 36), g: level(i / 6), b: level(i) };
+    }
+    for i in 0..24 {
+        let gray = 8 + 10 * i as u8;
+        table[232 + i] = Rgb { r: gray, g: gray, b: gray };
+    }
+    table[NamedColor::Foreground as usize] = palette[16];
+    table[NamedColor::Background as usize] = palette[17];
+    table[NamedColor::Cursor as usize] = palette[16];
+    for i in 0..8 {
+        table[NamedColor::DimBlack as usize + i] = dim(palette[i]);
+    }
+    table[NamedColor::BrightForeground as usize] = palette[16];
+    table[NamedColor::DimForeground as usize] = dim(palette[16]);
+
+    for (i, color) in table.iter_mut().enumerate() {
+        if let Some(rgb) = colors[i] {
+            *color = rgb;
+        }
+    }
+    table
+}
+
+/// Resolve a cell color with the color table
+fn color_rgb(color: &Color, colors: &ColorTable) -> Rgb {
+    match *color {
+        Color::Spec(rgb) => rgb,
+        Color::Named(named) => colors[named as usize],
+        Color::Indexed(idx) => colors[idx as usize],
+    }
+}
+
+/// Convert Alacritty Cell to CCell
+fn cell_to_ccell(cell: &Cell, colors: &ColorTable) -> CCell {
+    let c = cell.c as u32;
+    let Rgb { r: fg_r, g: fg_g, b: fg_b } = color_rgb(&cell.fg, colors);
+    let Rgb { r: bg_r, g: bg_g, b: bg_b } = color_rgb(&cell.bg, colors);
+
     // Convert flags
     let mut flags = 0u16;
     if cell.flags.contains(Flags::BOLD) {
@@ -156,38 +358,135 @@ fn cell_to_ccell(cell: &Cell) -> CCell {
     if cell.flags.contains(Flags::INVERSE) {
         flags |= 8;
     }
+    if cell.flags.contains(Flags::STRIKEOUT) {
+        flags |= 16;
+    }
+    if cell.flags.contains(Flags::WIDE_CHAR) {
+        flags |= 32;
+    }
+    if cell.flags.contains(Flags::WIDE_CHAR_SPACER) {
+        flags |= 64;
+    }
+    if cell.flags.contains(Flags::WRAPLINE) {
+        flags |= 128;
+    }
+    if cell.flags.contains(Flags::DIM) {
+        flags |= 256;
+    }
+    if cell.flags.contains(Flags::HIDDEN) {
+        flags |= 512;
+    }
+    if cell.hyperlink().is_some() {
+        flags |= 1024;
+    }
 
     CCell {
         c,
         fg_r, fg_g, fg_b,
         bg_r, bg_g, bg_b,
         flags,
+        fg_index: color_index(&cell.fg),
+        bg_index: color_index(&cell.bg),
     }
 }
 
-/// Create a new terminal instance
+/// Fill options with the defaults used by terminal_new
 #[no_mangle]
-pub extern "C" fn terminal_new(cols: c_uint, rows: c_uint) -> *mut CTerminal {
-    let size = CTermSize {
-        columns: cols,
-        screen_lines: rows,
-    };
-    
+pub extern "C" fn terminal_default_options(options: *mut CTerminalOptions) -> c_int {
+    if options.is_null() {
+        return ERROR_NULL_POINTER;
+    }
+
     let config = Config::default();
-    let term = Term::new(config, &size, VoidListener);
-    let parser = Processor::new();
-    
-    let terminal = Box::new(CTerminal { term, parser, size });
-    Box::into_raw(terminal)
+    let shape = config.default_cursor_style.shape;
+    unsafe {
+        *options = CTerminalOptions {
+            scrollback_lines: config.scrolling_history as c_uint,
+            max_columns: DEFAULT_MAX_SIZE,
+            max_lines: DEFAULT_MAX_SIZE,
+            cursor_shape: state::CURSOR_SHAPES.iter().position(|&s| s == shape).unwrap_or(0) as u8,
+            cursor_blinking: config.default_cursor_style.blinking as u8,
+            kitty_keyboard: config.kitty_keyboard as u8,
+            osc52: OSC52_POLICIES.iter().position(|&p| p == config.osc52).unwrap_or(0) as u8,
+            clipboard_limit: DEFAULT_CLIPBOARD_LIMIT,
+            semantic_escape_chars: std::ptr::null(),
+            palette: DEFAULT_PALETTE.map(|Rgb { r, g, b }| CRgb { r, g, b }),
+        };
+    }
+    0
+}
+
+/// Create a new terminal instance with the default options
+#[no_mangle]
+pub extern "C" fn terminal_new(cols: c_uint, rows: c_uint) -> *mut CTerminal {
+    let mut options = std::mem::MaybeUninit::<CTerminalOptions>::uninit();
+    terminal_default_options(options.as_mut_ptr());
+    let options = unsafe { options.assume_init() };
+    terminal_new_with_options(cols, rows, &options)
+}
+
+/// Create a new terminal instance. Returns NULL if the size is zero or
+/// exceeds the maximum size of the options, or if creating it panics, and
+/// records the detail for terminal_last_error but for a NULL options.
+#[no_mangle]
+pub extern "C" fn terminal_new_with_options(
+    cols: c_uint,
+    rows: c_uint,
+    options: *const CTerminalOptions,
+) -> *mut CTerminal {
+    if options.is_null() {
+        return std::ptr::null_mut();
+    }
+
+    let created = panic::catch_unwind(|| unsafe {
+        let options = &*options;
+        let max_size = CTermSize { columns: options.max_columns, screen_lines: options.max_lines };
+        if let Some(detail) = check_size(&max_size, cols, rows) {
+            fail(ERROR_INVALID_SIZE, detail);
+            return std::ptr::null_mut();
+        }
+
+        let size = CTermSize {
+            columns: cols,
+            screen_lines: rows,
+        };
+        let config = config_from_options(options);
+        let term = new_term(&size, &config);
+        let parser = Processor::new();
+
+        let terminal = Box::new(CTerminal {
+            term,
+            parser,
+            size,
+            state: TrackedState::default(),
+            tail: SequenceTail::default(),
+            scanner: ReportScanner::default(),
+            checkpoints: Checkpoints::default(),
+            clipboard: ClipboardRequests::new(config.osc52, options.clipboard_limit as usize),
+            replies: Vec::new(),
+            config,
+            palette: options.palette.map(|CRgb { r, g, b }| Rgb { r, g, b }),
+            max_size,
+            alternate: None,
+            poisoned: OnceLock::new(),
+        });
+        Box::into_raw(terminal)
+    });
+    created.unwrap_or_else(|payload| {
+        fail(ERROR_POISONED, format!("panic: {}", panic_message(&*payload)));
+        std::ptr::null_mut()
+    })
 }
 
 /// Free a terminal instance
 #[no_mangle]
 pub extern "C" fn terminal_free(terminal: *mut CTerminal) {
     if !terminal.is_null() {
-        unsafe {
+        // A poisoned terminal is freed as well. If dropping it panics, the
+        // rest of it is leaked.
+        let _ = panic::catch_unwind(AssertUnwindSafe(|| unsafe {
             let _ = Box::from_raw(terminal);
-        }
+        }));
     }
 }
 
@@ -198,21 +497,70 @@ pub extern "C" fn terminal_process_bytes(
     input: *const u8,
     input_len: usize,
 ) -> c_int {
-    if terminal.is_null() || input.is_null() {
-        return -1;
-    }
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || input.is_null() {
+            return ERROR_NULL_POINTER;
+        }
 
-    unsafe {
-        let terminal = &mut *terminal;
-        let input_slice = slice::from_raw_parts(input, input_len);
-        
-        // Process the input through VTE parser
-        terminal.parser.advance(&mut terminal.term, input_slice);
+        unsafe {
+            let terminal = &mut *terminal;
+            let input_slice = slice::from_raw_parts(input, input_len);
+            checkpoint::before_change(terminal);
+
+            // Checkpoints sharing the history copy the lines the input
+            // pushes out of it after every chunk
+            let chunk = if terminal.checkpoints.sharing() {
+                checkpoint::CHUNK
+            } else {
+                input_slice.len().max(1)
+            };
+            for part in input_slice.chunks(chunk) {
+                // Process the input through VTE parser
+                let mut tracker = Tracker {
+                    term: &mut terminal.term,
+                    state: &mut terminal.state,
+                    clipboard: &mut terminal.clipboard,
+                    palette: &terminal.palette,
+                    replies: &mut terminal.replies,
+                    checkpoints: Some(&mut terminal.checkpoints),
+                };
+                shell::advance(&mut terminal.parser, &mut terminal.scanner, &mut tracker, part);
+                terminal.tail.update(part, &terminal.parser);
+                terminal.checkpoints.trim(&mut terminal.term, terminal.config.scrolling_history);
+            }
         
-        // For simplicity, assume all lines might have changed
-        // In a real implementation, you'd track damage more precisely
-        terminal.size.screen_lines as c_int
-    }
+            // For simplicity, assume all lines might have changed
+            // In a real implementation, you'd track damage more precisely
+            terminal.size.screen_lines as c_int
+        }
+    })
+}
+
+/// Drop the scrollback history, as ESC [ 3 J does
+#[no_mangle]
+pub extern "C" fn terminal_clear_history(terminal: *mut CTerminal) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            checkpoint::before_change(terminal);
+
+            let mut tracker = Tracker {
+                term: &mut terminal.term,
+                state: &mut terminal.state,
+                clipboard: &mut terminal.clipboard,
+                palette: &terminal.palette,
+                replies: &mut terminal.replies,
+                checkpoints: Some(&mut terminal.checkpoints),
+            };
+            tracker.clear_screen(ClearMode::Saved);
+            terminal.checkpoints.trim(&mut terminal.term, terminal.config.scrolling_history);
+        }
+        0
+    })
 }
 
 /// Get a cell at the specified position
@@ -222,21 +570,23 @@ pub extern "C" fn terminal_get_cell(
     x: c_uint,
     y: c_uint,
 ) -> CCell {
-    if terminal.is_null() {
-        return CCell::default();
-    }
-
-    unsafe {
-        let terminal = &*terminal;
-        
-        if x >= terminal.size.columns || y >= terminal.size.screen_lines {
+    guard(terminal, CCell::default(), move || {
+        if terminal.is_null() {
             return CCell::default();
         }
 
-        let point = Point::new(Line(y as i32), Column(x as usize));
-        let cell = &terminal.term.grid()[point];
-        cell_to_ccell(cell)
-    }
+        unsafe {
+            let terminal = &*terminal;
+        
+            if x >= terminal.size.columns || y >= terminal.size.screen_lines {
+                return CCell::default();
+            }
+
+            let point = Point::new(Line(y as i32), Column(x as usize));
+            let cell = &terminal.term.grid()[point];
+            cell_to_ccell(cell, &terminal.colors())
+        }
+    })
 }
 
 /// Get all cells for a specific line
@@ -247,28 +597,209 @@ pub extern "C" fn terminal_get_line(
     output_cells: *mut CCell,
     max_cells: usize,
 ) -> c_int {
-    if terminal.is_null() || output_cells.is_null() {
-        return -1;
-    }
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || output_cells.is_null() {
+            return ERROR_NULL_POINTER;
+        }
 
-    unsafe {
-        let terminal = &*terminal;
+        unsafe {
+            let terminal = &*terminal;
         
-        if y >= terminal.size.screen_lines {
-            return -1;
-        }
+            if y >= terminal.size.screen_lines {
+                let detail = format!("line {} outside the {} screen lines", y, terminal.size.screen_lines);
+                return fail(ERROR_OUT_OF_BOUNDS, detail);
+            }
 
-        let cols = std::cmp::min(terminal.size.columns as usize, max_cells);
-        let output_slice = slice::from_raw_parts_mut(output_cells, cols);
+            let cols = std::cmp::min(terminal.size.columns as usize, max_cells);
+            let output_slice = slice::from_raw_parts_mut(output_cells, cols);
+            let colors = terminal.colors();
         
-        for x in 0..cols {
-            let point = Point::new(Line(y as i32), Column(x as usize));
-            let cell = &terminal.term.grid()[point];
-            output_slice[x] = cell_to_ccell(cell);
-        }
+            for x in 0..cols {
+                let point = Point::new(Line(y as i32), Column(x as usize));
+                let cell = &terminal.term.grid()[point];
+                output_slice[x] = cell_to_ccell(cell, &colors);
+            }
         
-        cols as c_int
-    }
+            cols as c_int
+        }
+    })
+}
+
+/// Get the number of lines in the scrollback history
+#[no_mangle]
+pub extern "C" fn terminal_get_history_size(terminal: *const CTerminal) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            terminal.term.grid().history_size() as c_int
+        }
+    })
+}
+
+/// Get all cells for a scrollback line, where offset 0 is the oldest line
+#[no_mangle]
+pub extern "C" fn terminal_get_history_line(
+    terminal: *const CTerminal,
+    offset: c_uint,
+    output_cells: *mut CCell,
+    max_cells: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || output_cells.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            let history_size = terminal.term.grid().history_size();
+
+            if offset as usize >= history_size {
+                let detail = format!("history line {} outside the {} history lines", offset, history_size);
+                return fail(ERROR_OUT_OF_BOUNDS, detail);
+            }
+
+            let line = Line(offset as i32 - history_size as i32);
+            let cols = std::cmp::min(terminal.size.columns as usize, max_cells);
+            let output_slice = slice::from_raw_parts_mut(output_cells, cols);
+            let colors = terminal.colors();
+
+            for x in 0..cols {
+                let cell = &terminal.term.grid()[Point::new(line, Column(x))];
+                output_slice[x] = cell_to_ccell(cell, &colors);
+            }
+
+            cols as c_int
+        }
+    })
+}
+
+/// C-compatible run of cells linked to the same OSC 8 hyperlink
+#[repr(C)]
+#[derive(Debug, Clone, Copy)]
+pub struct CHyperlinkSpan {
+    pub start: c_uint, // First column
+    pub end: c_uint,   // Column after the last
+    pub id: *const u8, // UTF-8, not NUL-terminated
+    pub id_len: usize,
+    pub uri: *const u8,
+    pub uri_len: usize,
+}
+
+/// Get the hyperlinks of a line as runs of cells. Line 0 is the top of the
+/// screen and negative lines are the scrollback, -1 being the newest. The
+/// strings point into the terminal and stay valid until it changes.
+/// Returns the number of runs, which may exceed max_spans.
+#[no_mangle]
+pub extern "C" fn terminal_get_hyperlinks(
+    terminal: *const CTerminal,
+    line: i32,
+    spans: *mut CHyperlinkSpan,
+    max_spans: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || (spans.is_null() && max_spans > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            let grid = terminal.term.grid();
+            let history_size = grid.history_size() as i32;
+
+            if line < -history_size || line >= terminal.size.screen_lines as i32 {
+                let detail = format!("line {} outside the terminal", line);
+                return fail(ERROR_OUT_OF_BOUNDS, detail);
+            }
+
+            let row = &grid[Line(line)];
+            let cols = terminal.size.columns as usize;
+            let mut count = 0;
+            let mut x = 0;
+            while x < cols {
+                let Some(link) = row[Column(x)].hyperlink() else {
+                    x += 1;
+                    continue;
+                };
+
+                let start = x;
+                while x < cols && row[Column(x)].hyperlink().as_ref() == Some(&link) {
+                    x += 1;
+                }
+
+                // The strings are shared with the cells, which outlive link
+                if count < max_spans {
+                    *spans.add(count) = CHyperlinkSpan {
+                        start: start as c_uint,
+                        end: x as c_uint,
+                        id: link.id().as_ptr(),
+                        id_len: link.id().len(),
+                        uri: link.uri().as_ptr(),
+                        uri_len: link.uri().len(),
+                    };
+                }
+                count += 1;
+            }
+
+            count as c_int
+        }
+    })
+}
+
+/// C-compatible shell integration mark
+#[repr(C)]
+#[derive(Debug, Clone, Copy)]
+pub struct CMark {
+    pub line: i32,      // 0 is the top of the screen, negative lines are the scrollback
+    pub column: c_uint,
+    pub kind: u8,       // MARK_*
+    pub has_exit_code: u8,
+    pub exit_code: i32, // Exit code of MARK_COMMAND_END, if has_exit_code
+}
+
+/// Get the OSC 133 marks of the screen and its scrollback, oldest first.
+/// The alternate screen has no marks. Returns the number of marks, which
+/// may exceed max_marks.
+#[no_mangle]
+pub extern "C" fn terminal_get_marks(
+    terminal: *const CTerminal,
+    marks: *mut CMark,
+    max_marks: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || (marks.is_null() && max_marks > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            if terminal.term.mode().contains(TermMode::ALT_SCREEN) {
+                return 0;
+            }
+
+            let state = &terminal.state.marks;
+            let history_size = terminal.term.grid().history_size() as i64;
+            let oldest = state.scrolled - history_size;
+
+            let mut count = 0;
+            for mark in state.list.iter().filter(|m| m.line >= oldest) {
+                if count < max_marks {
+                    *marks.add(count) = CMark {
+                        line: (mark.line - state.scrolled) as i32,
+                        column: mark.column as c_uint,
+                        kind: mark.kind,
+                        has_exit_code: mark.exit_code.is_some() as u8,
+                        exit_code: mark.exit_code.unwrap_or(0),
+                    };
+                }
+                count += 1;
+            }
+            count as c_int
+        }
+    })
 }
 
 /// Resize the terminal
@@ -278,17 +809,42 @@ pub extern "C" fn terminal_resize(
     cols: c_uint,
     rows: c_uint,
 ) -> c_int {
-    if terminal.is_null() {
-        return -1;
-    }
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
 
-    unsafe {
-        let terminal = &mut *terminal;
-        terminal.size.columns = cols;
-        terminal.size.screen_lines = rows;
-        terminal.term.resize(terminal.size);
-        0
-    }
+        unsafe {
+            let terminal = &mut *terminal;
+            if let Some(detail) = check_size(&terminal.max_size, cols, rows) {
+                return fail(ERROR_INVALID_SIZE, detail);
+            }
+
+            checkpoint::before_change(terminal);
+            // Resizing rewraps the history
+            terminal.checkpoints.detach(&mut terminal.term);
+            // Marks keep their place in the text, which rewrapping lines
+            // moves to other lines and columns
+            let marks = &mut terminal.state.marks;
+            let (history, positions) = state::with_primary(&mut terminal.term, |grid| {
+                (grid.history_size(), marks.text_positions(grid))
+            });
+
+            terminal.size.columns = cols;
+            terminal.size.screen_lines = rows;
+            terminal.term.resize(terminal.size);
+            // Resizing resets the scrolling region
+            terminal.state.scroll_region = None;
+
+            let marks = &mut terminal.state.marks;
+            state::with_primary(&mut terminal.term, |grid| {
+                marks.scrolled += grid.history_size() as i64 - history as i64;
+                marks.reflow(grid, positions);
+            });
+            terminal.checkpoints.trim(&mut terminal.term, terminal.config.scrolling_history);
+            0
+        }
+    })
 }
 
 /// Get terminal size
@@ -298,16 +854,221 @@ pub extern "C" fn terminal_get_size(
     cols: *mut c_uint,
     rows: *mut c_uint,
 ) -> c_int {
-    if terminal.is_null() || cols.is_null() || rows.is_null() {
-        return -1;
-    }
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || cols.is_null() || rows.is_null() {
+            return ERROR_NULL_POINTER;
+        }
 
-    unsafe {
-        let terminal = &*terminal;
-        *cols = terminal.size.columns;
-        *rows = terminal.size.screen_lines;
+        unsafe {
+            let terminal = &*terminal;
+            *cols = terminal.size.columns;
+            *rows = terminal.size.screen_lines;
+            0
+        }
+    })
+}
+
+/// Get the attributes applied to newly written cells
+#[no_mangle]
+pub extern "C" fn terminal_get_pen(terminal: *const CTerminal, pen: *mut CCell) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || pen.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            *pen = cell_to_ccell(&terminal.term.grid().cursor.template, &terminal.colors());
+            0
+        }
+    })
+}
+
+/// Copy the window title as UTF-8 into buf, truncated to max_len bytes.
+/// Returns the full length of the title, which is 0 if none was set.
+#[no_mangle]
+pub extern "C" fn terminal_get_title(
+    terminal: *const CTerminal,
+    buf: *mut u8,
+    max_len: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || (buf.is_null() && max_len > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            copy_str(terminal.state.title.as_deref().unwrap_or(""), buf, max_len)
+        }
+    })
+}
+
+/// Copy the URL of the working directory reported with OSC 7, as sent,
+/// into buf, truncated to max_len bytes. Returns the full length of the
+/// URL, which is 0 if none was reported.
+#[no_mangle]
+pub extern "C" fn terminal_get_working_directory(
+    terminal: *const CTerminal,
+    buf: *mut u8,
+    max_len: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || (buf.is_null() && max_len > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            copy_str(terminal.state.working_directory.as_deref().unwrap_or(""), buf, max_len)
+        }
+    })
+}
+
+/// Copy the colors in use into colors, in the order of alacritty's color
+/// table: the 256 indexed colors, the default foreground and background,
+/// the cursor color and the dim and bright variants. Returns the number of
+/// colors, which may exceed max_colors.
+#[no_mangle]
+pub extern "C" fn terminal_get_colors(
+    terminal: *const CTerminal,
+    colors: *mut CRgb,
+    max_colors: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || (colors.is_null() && max_colors > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let table = (*terminal).colors();
+            for (i, &Rgb { r, g, b }) in table.iter().take(max_colors).enumerate() {
+                *colors.add(i) = CRgb { r, g, b };
+            }
+            table.len() as c_int
+        }
+    })
+}
+
+/// Copy the answers to the application, such as those to color queries,
+/// into buf and drop them if it holds them all. Returns their length.
+#[no_mangle]
+pub extern "C" fn terminal_take_replies(terminal: *mut CTerminal, buf: *mut u8, max_len: usize) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || (buf.is_null() && max_len > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let replies = &mut (*terminal).replies;
+            let len = replies.len();
+            if len > 0 && len <= max_len {
+                std::ptr::copy_nonoverlapping(replies.as_ptr(), buf, len);
+                replies.clear();
+            }
+            len as c_int
+        }
+    })
+}
+
+/// C-compatible OSC 52 clipboard request
+#[repr(C)]
+#[derive(Debug, Clone, Copy)]
+pub struct CClipboardRequest {
+    pub selection: u8,    // Selection parameter, such as 'c' for the clipboard
+    pub load: u8,         // 1 to load the selection, 0 to store data
+    pub st: u8,           // 1 if the request ended with ESC \ rather than BEL
+    pub data: *const u8,  // Base64 text to store, not NUL-terminated
+    pub data_len: usize,
+}
+
+/// Get the OSC 52 requests allowed by the policy that were received since
+/// terminal_clear_clipboard_requests, oldest first. The data points into the
+/// terminal and stays valid until it changes. Returns the number of
+/// requests, which may exceed max_requests.
+#[no_mangle]
+pub extern "C" fn terminal_get_clipboard_requests(
+    terminal: *const CTerminal,
+    requests: *mut CClipboardRequest,
+    max_requests: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || (requests.is_null() && max_requests > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let pending = &(*terminal).clipboard.pending;
+            for (i, request) in pending.iter().take(max_requests).enumerate() {
+                let data = request.data.as_deref().unwrap_or(&[]);
+                *requests.add(i) = CClipboardRequest {
+                    selection: request.selection,
+                    load: request.data.is_none() as u8,
+                    st: request.st as u8,
+                    data: data.as_ptr(),
+                    data_len: data.len(),
+                };
+            }
+            pending.len() as c_int
+        }
+    })
+}
+
+/// Drop the OSC 52 requests returned by terminal_get_clipboard_requests
+#[no_mangle]
+pub extern "C" fn terminal_clear_clipboard_requests(terminal: *mut CTerminal) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            (*terminal).clipboard.pending.clear();
+        }
         0
-    }
+    })
+}
+
+/// Terminal modes reported by terminal_get_mode, mirroring alacritty's TermMode
+const MODE_FLAGS: [(TermMode, u32); 18] = [
+    (TermMode::SHOW_CURSOR, 1 << 0),
+    (TermMode::APP_CURSOR, 1 << 1),
+    (TermMode::APP_KEYPAD, 1 << 2),
+    (TermMode::MOUSE_REPORT_CLICK, 1 << 3),
+    (TermMode::BRACKETED_PASTE, 1 << 4),
+    (TermMode::SGR_MOUSE, 1 << 5),
+    (TermMode::MOUSE_MOTION, 1 << 6),
+    (TermMode::LINE_WRAP, 1 << 7),
+    (TermMode::LINE_FEED_NEW_LINE, 1 << 8),
+    (TermMode::ORIGIN, 1 << 9),
+    (TermMode::INSERT, 1 << 10),
+    (TermMode::FOCUS_IN_OUT, 1 << 11),
+    (TermMode::ALT_SCREEN, 1 << 12),
+    (TermMode::MOUSE_DRAG, 1 << 13),
+    (TermMode::UTF8_MOUSE, 1 << 14),
+    (TermMode::ALTERNATE_SCROLL, 1 << 15),
+    (TermMode::URGENCY_HINTS, 1 << 16),
+    (TermMode::DISAMBIGUATE_ESC_CODES, 1 << 17),
+];
+
+/// Get the active terminal modes as a MODE_* bitmask
+#[no_mangle]
+pub extern "C" fn terminal_get_mode(terminal: *const CTerminal, mode: *mut u32) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || mode.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            let term_mode = terminal.term.mode();
+            *mode = MODE_FLAGS
+                .iter()
+                .filter(|(flag, _)| term_mode.contains(*flag))
+                .fold(0, |acc, (_, bit)| acc | bit);
+            0
+        }
+    })
 }
 
 /// Get cursor position
@@ -317,15 +1078,401 @@ pub extern "C" fn terminal_get_cursor(
     x: *mut c_uint,
     y: *mut c_uint,
 ) -> c_int {
-    if terminal.is_null() || x.is_null() || y.is_null() {
-        return -1;
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || x.is_null() || y.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            let cursor = terminal.term.grid().cursor.point;
+            *x = cursor.column.0 as c_uint;
+            *y = cursor.line.0 as c_uint;
+            0
+        }
+    })
+}
+
+/// Get whether the cursor waits past the last column, where the next
+/// character first wraps to the next line
+#[no_mangle]
+pub extern "C" fn terminal_get_wrap_pending(terminal: *const CTerminal, pending: *mut u8) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || pending.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            *pending = (*terminal).term.grid().cursor.input_needs_wrap as u8;
+            0
+        }
+    })
+}
+
+/// Get the scrolling region set with DECSTBM as its first line and the line
+/// after its last, which are 0 and the number of lines without a region
+#[no_mangle]
+pub extern "C" fn terminal_get_scroll_region(
+    terminal: *const CTerminal,
+    top: *mut c_uint,
+    bottom: *mut c_uint,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || top.is_null() || bottom.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &*terminal;
+            let lines = terminal.size.screen_lines;
+            let (first, last) = terminal.state.scroll_region.unwrap_or((1, lines as usize));
+            *top = first as c_uint - 1;
+            *bottom = (last as c_uint).min(lines);
+            0
+        }
+    })
+}
+
+/// Show the primary screen behind the alternate screen to the functions
+/// reading the screen, such as terminal_get_line and terminal_get_cursor,
+/// until terminal_end_primary. Nothing may change the terminal in between.
+/// Does nothing while the primary screen is shown.
+#[no_mangle]
+pub extern "C" fn terminal_begin_primary(terminal: *mut CTerminal) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            if terminal.alternate.is_none() {
+                terminal.alternate = state::show_primary(&mut terminal.term);
+            }
+            0
+        }
+    })
+}
+
+/// Show the alternate screen again after terminal_begin_primary
+#[no_mangle]
+pub extern "C" fn terminal_end_primary(terminal: *mut CTerminal) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            if let Some(alternate) = terminal.alternate.take() {
+                state::show_alternate(&mut terminal.term, alternate);
+            }
+            0
+        }
+    })
+}
+
+/// Flag for terminal_save_state leaving out the scrollback history
+pub const SAVE_STATE_NO_HISTORY: c_uint = 1;
+
+/// Serialize the complete terminal state. The buffer stored in data must be
+/// released with terminal_free_state.
+#[no_mangle]
+pub extern "C" fn terminal_save_state(
+    terminal: *mut CTerminal,
+    flags: c_uint,
+    data: *mut *mut u8,
+    len: *mut usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || data.is_null() || len.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            let history = flags & SAVE_STATE_NO_HISTORY == 0;
+            match state::save(terminal, history) {
+                Some(state) => {
+                    let state = state.into_boxed_slice();
+                    *len = state.len();
+                    *data = Box::into_raw(state) as *mut u8;
+                    0
+                },
+                None => fail(ERROR_INVALID_STATE, "failed to encode the state".into()),
+            }
+        }
+    })
+}
+
+/// Free a buffer returned by terminal_save_state
+#[no_mangle]
+pub extern "C" fn terminal_free_state(data: *mut u8, len: usize) {
+    if !data.is_null() {
+        let _ = panic::catch_unwind(AssertUnwindSafe(|| unsafe {
+            let _ = Box::from_raw(slice::from_raw_parts_mut(data, len) as *mut [u8]);
+        }));
     }
+}
 
-    unsafe {
-        let terminal = &*terminal;
-        let cursor = terminal.term.grid().cursor.point;
-        *x = cursor.column.0 as c_uint;
-        *y = cursor.line.0 as c_uint;
-        0
+/// Replace the terminal state, including its size, with serialized state.
+/// The terminal is unchanged if the state is invalid.
+#[no_mangle]
+pub extern "C" fn terminal_restore_state(
+    terminal: *mut CTerminal,
+    data: *const u8,
+    len: usize,
+) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || data.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            let data = slice::from_raw_parts(data, len);
+            checkpoint::before_change(terminal);
+            terminal.checkpoints.detach(&mut terminal.term);
+            let result = match state::restore(terminal, data) {
+                Some(()) => 0,
+                None => fail(ERROR_INVALID_STATE, "invalid or incompatible state".into()),
+            };
+            terminal.checkpoints.trim(&mut terminal.term, terminal.config.scrolling_history);
+            result
+        }
+    })
+}
+
+/// Create a checkpoint of the terminal state. Taking it copies nothing: the
+/// screens are copied when the terminal changes next, and checkpoints
+/// without changes in between share the copy. The scrollback is shared with
+/// the terminal, which copies its oldest lines into the checkpoint only as
+/// they leave the scrollback, and copies all the lines still shared before
+/// a resize, clearing the scrollback or a reset. Rolling back moves the
+/// shared lines into place instead of copying them.
+#[no_mangle]
+pub extern "C" fn terminal_checkpoint(terminal: *mut CTerminal, id: *mut u64) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() || id.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            *id = terminal.checkpoints.create();
+            0
+        }
+    })
+}
+
+/// Restore the terminal state of a checkpoint
+#[no_mangle]
+pub extern "C" fn terminal_rollback(terminal: *mut CTerminal, id: u64) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            if checkpoint::rollback(terminal, id) {
+                0
+            } else {
+                fail(ERROR_UNKNOWN_CHECKPOINT, format!("unknown checkpoint {}", id))
+            }
+        }
+    })
+}
+
+/// Free the state held by a checkpoint
+#[no_mangle]
+pub extern "C" fn terminal_release_checkpoint(terminal: *mut CTerminal, id: u64) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let terminal = &mut *terminal;
+            if terminal.checkpoints.release(id) {
+                // Without the checkpoint the scrollback may return to its size
+                terminal.checkpoints.trim(&mut terminal.term, terminal.config.scrolling_history);
+                0
+            } else {
+                fail(ERROR_UNKNOWN_CHECKPOINT, format!("unknown checkpoint {}", id))
+            }
+        }
+    })
+}
+
+/// Copy the detail of the last failure on the calling thread as UTF-8 into
+/// buf, truncated to max_len bytes. Returns the full length of the detail.
+/// Call it on the thread of the failing call, before calling the library
+/// again.
+#[no_mangle]
+pub extern "C" fn terminal_last_error(buf: *mut u8, max_len: usize) -> c_int {
+    if buf.is_null() && max_len > 0 {
+        return ERROR_NULL_POINTER;
+    }
+
+    panic::catch_unwind(AssertUnwindSafe(|| {
+        LAST_ERROR.with(|last_error| unsafe { copy_str(&last_error.borrow(), buf, max_len) })
+    }))
+    .unwrap_or(ERROR_POISONED)
+}
+
+/// Panic while handling terminal, which poisons it. Only built with the
+/// test-panic feature, to test how panics are contained.
+#[cfg(feature = "test-panic")]
+#[no_mangle]
+pub extern "C" fn terminal_test_panic(terminal: *mut CTerminal) -> c_int {
+    guard(terminal, ERROR_POISONED, move || {
+        if terminal.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+        panic!("test panic")
+    })
+}
+
+/// Escape sequence parser without a terminal
+pub struct CParser {
+    parser: Parser,
+    events: Events,
+    /// Message of the panic that poisoned the parser, like
+    /// CTerminal::poisoned
+    poisoned: OnceLock<String>,
+}
+
+/// C-compatible parser event. The pointers point into the parser and stay
+/// valid until parser_clear_events.
+#[repr(C)]
+pub struct CParserEvent {
+    pub kind: u8,               // PARSER_EVENT_*
+    pub action: u8,             // Final byte of CSI, DCS and ESC, control of EXECUTE
+    pub ignore: u8,             // 1 if there were more parameters or intermediates than kept
+    pub bell_terminated: u8,    // 1 if the OSC string ended with BEL
+    pub truncated: u8,          // 1 if DCS data was dropped
+    pub intermediates: *const u8,
+    pub intermediates_len: usize,
+    pub params: *const u16,     // Parameter values, each followed by its subparameters
+    pub param_lens: *const u8,  // Number of values of each parameter
+    pub params_len: usize,      // Number of parameters
+    pub data: *const u8,        // PRINT text, OSC parameters joined by ';' or DCS data
+    pub data_len: usize,
+}
+
+/// Run the body of a function on parser like guard does for a terminal,
+/// recording the detail of a panic for terminal_last_error
+fn parser_guard<T>(parser: *const CParser, failed: T, f: impl FnOnce() -> T) -> T {
+    if let Some(message) = unsafe { parser.as_ref() }.and_then(|p| p.poisoned.get()) {
+        fail(ERROR_POISONED, format!("panic: {}", message));
+        return failed;
+    }
+
+    panic::catch_unwind(AssertUnwindSafe(f)).unwrap_or_else(|payload| {
+        let message = panic_message(&*payload);
+        if let Some(parser) = unsafe { parser.as_ref() } {
+            let _ = parser.poisoned.set(message.to_string());
+        }
+        fail(ERROR_POISONED, format!("panic: {}", message));
+        failed
+    })
+}
+
+/// Create a parser. Returns NULL if creating it panics, and records the
+/// detail for terminal_last_error.
+#[no_mangle]
+pub extern "C" fn parser_new() -> *mut CParser {
+    let created = panic::catch_unwind(|| {
+        Box::into_raw(Box::new(CParser {
+            parser: Parser::new(),
+            events: Events::default(),
+            poisoned: OnceLock::new(),
+        }))
+    });
+    created.unwrap_or_else(|payload| {
+        fail(ERROR_POISONED, format!("panic: {}", panic_message(&*payload)));
+        std::ptr::null_mut()
+    })
+}
+
+/// Free a parser
+#[no_mangle]
+pub extern "C" fn parser_free(parser: *mut CParser) {
+    if !parser.is_null() {
+        let _ = panic::catch_unwind(AssertUnwindSafe(|| unsafe {
+            let _ = Box::from_raw(parser);
+        }));
     }
-}
\ No newline at end of file
+}
+
+/// Parse input, queueing the events it completes. Returns the number of
+/// queued events.
+#[no_mangle]
+pub extern "C" fn parser_advance(parser: *mut CParser, input: *const u8, input_len: usize) -> c_int {
+    parser_guard(parser, ERROR_POISONED, move || {
+        if parser.is_null() || (input.is_null() && input_len > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let parser = &mut *parser;
+            if input_len > 0 {
+                let input = slice::from_raw_parts(input, input_len);
+                parser.parser.advance(&mut parser.events, input);
+            }
+            parser.events.list.len() as c_int
+        }
+    })
+}
+
+/// Get the queued events, oldest first. Returns the number of events, which
+/// may exceed max_events.
+#[no_mangle]
+pub extern "C" fn parser_get_events(
+    parser: *const CParser,
+    events: *mut CParserEvent,
+    max_events: usize,
+) -> c_int {
+    parser_guard(parser, ERROR_POISONED, move || {
+        if parser.is_null() || (events.is_null() && max_events > 0) {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            let list = &(*parser).events.list;
+            for (i, event) in list.iter().take(max_events).enumerate() {
+                *events.add(i) = CParserEvent {
+                    kind: event.kind,
+                    action: event.action,
+                    ignore: event.ignore as u8,
+                    bell_terminated: event.bell_terminated as u8,
+                    truncated: event.truncated as u8,
+                    intermediates: event.intermediates.as_ptr(),
+                    intermediates_len: event.intermediates.len(),
+                    params: event.params.as_ptr(),
+                    param_lens: event.param_lens.as_ptr(),
+                    params_len: event.param_lens.len(),
+                    data: event.data.as_ptr(),
+                    data_len: event.data.len(),
+                };
+            }
+            list.len() as c_int
+        }
+    })
+}
+
+/// Drop the events returned by parser_get_events
+#[no_mangle]
+pub extern "C" fn parser_clear_events(parser: *mut CParser) -> c_int {
+    parser_guard(parser, ERROR_POISONED, move || {
+        if parser.is_null() {
+            return ERROR_NULL_POINTER;
+        }
+
+        unsafe {
+            (*parser).events.list.clear();
+        }
+        0
+    })
+}
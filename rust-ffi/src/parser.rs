@@ -0,0 +1,125 @@
+//! Events of the vte parser without a terminal, for callers that classify
+//! or filter the escape sequences of a byte stream.
+//!
+//! The parser keeps its state between inputs, so a sequence may be split
+//! across them. Events are queued until the caller takes them.
+
+use alacritty_terminal::vte::{Params, Perform};
+
+/// Kinds of events, in the order of the PARSER_EVENT_* constants
+pub(crate) const EVENT_PRINT: u8 = 0;
+pub(crate) const EVENT_EXECUTE: u8 = 1;
+pub(crate) const EVENT_CSI: u8 = 2;
+pub(crate) const EVENT_OSC: u8 = 3;
+pub(crate) const EVENT_DCS: u8 = 4;
+pub(crate) const EVENT_ESC: u8 = 5;
+
+/// Longest DCS data kept, enough for a sixel image; the rest is dropped
+const MAX_DCS_DATA: usize = 1 << 20;
+
+/// Event of the parser
+#[derive(Debug, Default, Clone, PartialEq, Eq)]
+pub(crate) struct Event {
+    pub kind: u8,
+    /// Final byte of CSI, DCS and ESC, control of EXECUTE
+    pub action: u8,
+    /// The sequence had more parameters or intermediates than the parser
+    /// keeps
+    pub ignore: bool,
+    /// The OSC string ended with BEL rather than ST
+    pub bell_terminated: bool,
+    /// The DCS data was longer than MAX_DCS_DATA
+    pub truncated: bool,
+    pub intermediates: Vec<u8>,
+    /// Values of the CSI and DCS parameters, each followed by its
+    /// subparameters
+    pub params: Vec<u16>,
+    /// Number of values of each parameter in params
+    pub param_lens: Vec<u8>,
+    /// Text of PRINT, OSC parameters joined by ';' or DCS data
+    pub data: Vec<u8>,
+}
+
+impl Event {
+    fn with_params(kind: u8, params: &Params, intermediates: &[u8], ignore: bool, action: char) -> Self {
+        let mut event = Event {
+            kind,
+            action: action as u8,
+            ignore,
+            intermediates: intermediates.to_vec(),
+            ..Default::default()
+        };
+        for param in params.iter() {
+            event.param_lens.push(param.len() as u8);
+            event.params.extend_from_slice(param);
+        }
+        event
+    }
+}
+
+/// Events not yet taken by the caller
+#[derive(Debug, Default)]
+pub(crate) struct Events {
+    pub list: Vec<Event>,
+    /// DCS whose data is still being received
+    dcs: Option<Event>,
+}
+
+impl Perform for Events {
+    fn print(&mut self, c: char) {
+        // A run of characters is one event
+        let mut buf = [0; 4];
+        let text = c.encode_utf8(&mut buf).as_bytes();
+        match self.list.last_mut() {
+            Some(event) if event.kind == EVENT_PRINT => event.data.extend_from_slice(text),
+            _ => self.list.push(Event { kind: EVENT_PRINT, data: text.to_vec(), ..Default::default() }),
+        }
+    }
+
+    fn execute(&mut self, byte: u8) {
+        self.list.push(Event { kind: EVENT_EXECUTE, action: byte, ..Default::default() });
+    }
+
+    fn csi_dispatch(&mut self, params: &Params, intermediates: &[u8], ignore: bool, action: char) {
+        self.list.push(Event::with_params(EVENT_CSI, params, intermediates, ignore, action));
+    }
+
+    fn esc_dispatch(&mut self, intermediates: &[u8], ignore: bool, byte: u8) {
+        self.list.push(Event {
+            kind: EVENT_ESC,
+            action: byte,
+            ignore,
+            intermediates: intermediates.to_vec(),
+            ..Default::default()
+        });
+    }
+
+    fn osc_dispatch(&mut self, params: &[&[u8]], bell_terminated: bool) {
+        self.list.push(Event {
+            kind: EVENT_OSC,
+            bell_terminated,
+            data: params.join(&b';'),
+            ..Default::default()
+        });
+    }
+
+    fn hook(&mut self, params: &Params, intermediates: &[u8], ignore: bool, action: char) {
+        self.dcs = Some(Event::with_params(EVENT_DCS, params, intermediates, ignore, action));
+    }
+
+    fn put(&mut self, byte: u8) {
+        if let Some(dcs) = &mut self.dcs {
+            if dcs.data.len() < MAX_DCS_DATA {
+                dcs.data.push(byte);
+            } else {
+                dcs.truncated = true;
+            }
+        }
+    }
+
+    fn unhook(&mut self) {
+        if let Some(dcs) = self.dcs.take() {
+            self.list.push(dcs);
+        }
+    }
+}
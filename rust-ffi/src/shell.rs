@@ -0,0 +1,352 @@
+//! Shell integration reports that alacritty ignores: the marks of OSC 133
+//! and the working directory of OSC 7.
+//!
+//! The reports are found in the input before it reaches the parser and are
+//! applied when the parser reaches them, so that marks are recorded at the
+//! cursor position where they were sent. Marks are numbered from the first
+//! line of the terminal, so they keep pointing at their line as it scrolls
+//! into the history.
+
+use std::ops::Range;
+
+use alacritty_terminal::event::EventListener;
+use alacritty_terminal::grid::{Dimensions, Grid, GridCell};
+use alacritty_terminal::index::{Column, Line};
+use alacritty_terminal::term::cell::{Cell, Flags};
+use alacritty_terminal::term::TermMode;
+use alacritty_terminal::vte::ansi::Processor;
+use serde::{Deserialize, Serialize};
+
+use crate::state::Tracker;
+
+/// Kinds of marks, in the order of the MARK_* constants
+pub(crate) const MARK_PROMPT_START: u8 = 0;
+pub(crate) const MARK_COMMAND_START: u8 = 1;
+pub(crate) const MARK_OUTPUT_START: u8 = 2;
+pub(crate) const MARK_COMMAND_END: u8 = 3;
+
+/// Longest OSC string inspected, enough for a path in a file URL; longer
+/// strings are not reports
+const MAX_PAYLOAD: usize = 8192;
+
+/// Mark sent by the shell
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+pub(crate) struct Mark {
+    pub kind: u8,
+    /// Exit code of the command, for MARK_COMMAND_END
+    pub exit_code: Option<i32>,
+    /// Line counted from the first line the terminal had
+    pub line: i64,
+    pub column: usize,
+}
+
+/// Report found in the input
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) enum Report {
+    Mark(Mark),
+    /// URL of the working directory, as sent
+    WorkingDirectory(String),
+}
+
+/// Marks of the primary screen and its history, oldest first
+#[derive(Debug, Default, Clone, Serialize, Deserialize)]
+pub(crate) struct Marks {
+    /// Lines that scrolled off the top of the screen, so that screen line y
+    /// is line scrolled + y
+    pub scrolled: i64,
+    pub list: Vec<Mark>,
+}
+
+impl Marks {
+    /// Move the marks in region of the screen up by n lines. Lines leaving
+    /// a region at the top of the screen go to the history; elsewhere they
+    /// are lost.
+    pub fn scroll_up(&mut self, region: Range<usize>, n: usize) {
+        let n = n.min(region.len()) as i64;
+        let (start, end) = (self.scrolled + region.start as i64, self.scrolled + region.end as i64);
+        if region.start == 0 {
+            // The lines below the region stay where they are
+            self.scrolled += n;
+            for mark in self.list.iter_mut().filter(|m| m.line >= end) {
+                mark.line += n;
+            }
+            return;
+        }
+
+        self.list.retain(|m| m.line < start || m.line >= start + n);
+        for mark in self.list.iter_mut().filter(|m| m.line >= start && m.line < end) {
+            mark.line -= n;
+        }
+    }
+
+    /// Move the marks in region of the screen down by n lines, dropping
+    /// those pushed out at the bottom
+    pub fn scroll_down(&mut self, region: Range<usize>, n: usize) {
+        let n = n.min(region.len()) as i64;
+        let (start, end) = (self.scrolled + region.start as i64, self.scrolled + region.end as i64);
+        self.list.retain(|m| m.line < end - n || m.line >= end);
+        for mark in self.list.iter_mut().filter(|m| m.line >= start && m.line < end - n) {
+            mark.line += n;
+        }
+    }
+
+    /// Drop the marks of lines that left the history
+    pub fn prune(&mut self, history_size: usize) {
+        let oldest = self.scrolled - history_size as i64;
+        self.list.retain(|m| m.line >= oldest);
+    }
+
+    /// Positions of the marks in the text of grid, the primary grid, which
+    /// rewrapping its lines keeps: the unwrapped lines from the one of the
+    /// cursor and the offset in the unwrapped line
+    pub fn text_positions(&mut self, grid: &Grid<Cell>) -> Vec<(i64, usize)> {
+        self.prune(grid.history_size());
+        let starts = unwrapped_starts(grid);
+        let cursor = unwrapped_index(&starts, grid.cursor.point.line.0) as i64;
+        self.list
+            .iter()
+            .map(|mark| {
+                let line = (mark.line - self.scrolled) as i32;
+                let index = unwrapped_index(&starts, line);
+                let offset = (line - starts[index]) as usize * grid.columns() + mark.column;
+                (index as i64 - cursor, offset)
+            })
+            .collect()
+    }
+
+    /// Move the marks to their text positions in grid, the primary grid
+    /// after its lines were rewrapped, dropping those of lines that left it
+    pub fn reflow(&mut self, grid: &Grid<Cell>, positions: Vec<(i64, usize)>) {
+        let starts = unwrapped_starts(grid);
+        let cursor = unwrapped_index(&starts, grid.cursor.point.line.0) as i64;
+        let columns = grid.columns();
+        let end = grid.screen_lines() as i32;
+
+        let marks = std::mem::take(&mut self.list).into_iter().zip(positions);
+        for (mut mark, (lines, offset)) in marks {
+            let index = cursor + lines;
+            if index < 0 || index >= starts.len() as i64 {
+                continue;
+            }
+            let start = starts[index as usize];
+            let next = starts.get(index as usize + 1).copied().unwrap_or(end);
+
+            // Trailing blanks are not rewrapped, so the offset may be past
+            // the end of the line
+            let line = start + (offset / columns) as i32;
+            (mark.line, mark.column) = if line < next {
+                (line as i64, offset % columns)
+            } else {
+                ((next - 1) as i64, columns - 1)
+            };
+            mark.line += self.scrolled;
+            self.list.push(mark);
+        }
+    }
+}
+
+/// First line of each unwrapped line of grid, oldest first, where the
+/// oldest line of the history is the first
+fn unwrapped_starts(grid: &Grid<Cell>) -> Vec<i32> {
+    let top = -(grid.history_size() as i32);
+    let last = Column(grid.columns() - 1);
+    let mut starts = vec![top];
+    for line in top..grid.screen_lines() as i32 - 1 {
+        if !grid[Line(line)][last].flags.contains(Flags::WRAPLINE) {
+            starts.push(line + 1);
+        }
+    }
+    starts
+}
+
+/// Index in starts of the unwrapped line that line is part of
+fn unwrapped_index(starts: &[i32], line: i32) -> usize {
+    starts.partition_point(|&start| start <= line).saturating_sub(1)
+}
+
+#[derive(Debug, Default, Clone, Copy, PartialEq, Eq)]
+enum ScanState {
+    #[default]
+    Ground,
+    Escape,
+    Osc,
+}
+
+/// Finds OSC 7 and OSC 133 sequences in the input, keeping its state
+/// between inputs
+#[derive(Debug, Default, Clone)]
+pub(crate) struct ReportScanner {
+    state: ScanState,
+    payload: Vec<u8>,
+    /// The OSC string is longer than MAX_PAYLOAD
+    overflow: bool,
+}
+
+impl ReportScanner {
+    /// Find the reports in input, each with the offset after the byte that
+    /// ends it. Line and column of marks are filled in by the caller.
+    pub fn scan(&mut self, input: &[u8]) -> Vec<(usize, Report)> {
+        let mut found = Vec::new();
+        for (i, &b) in input.iter().enumerate() {
+            self.state = match (self.state, b) {
+                (ScanState::Ground, 0x1b) | (ScanState::Escape, 0x1b) => ScanState::Escape,
+                (ScanState::Ground, _) => ScanState::Ground,
+                (ScanState::Escape, b']') => {
+                    self.payload.clear();
+                    self.overflow = false;
+                    ScanState::Osc
+                },
+                (ScanState::Escape, _) => ScanState::Ground,
+                // Like the parser, ESC ends the string before starting a
+                // new sequence such as the ST of ESC \
+                (ScanState::Osc, 0x07) | (ScanState::Osc, 0x1b) => {
+                    if let Some(report) = self.report() {
+                        found.push((i + 1, report));
+                    }
+                    if b == 0x1b { ScanState::Escape } else { ScanState::Ground }
+                },
+                (ScanState::Osc, 0x18) | (ScanState::Osc, 0x1a) => ScanState::Ground,
+                (ScanState::Osc, _) => {
+                    if self.payload.len() < MAX_PAYLOAD {
+                        self.payload.push(b);
+                    } else {
+                        self.overflow = true;
+                    }
+                    ScanState::Osc
+                },
+            };
+        }
+        found
+    }
+
+    /// Parse the finished OSC string as a report
+    fn report(&self) -> Option<Report> {
+        if self.overflow {
+            return None;
+        }
+        let payload = std::str::from_utf8(&self.payload).ok()?;
+        if let Some(url) = payload.strip_prefix("7;") {
+            return Some(Report::WorkingDirectory(url.to_owned()));
+        }
+
+        let mut params = payload.split(';');
+        if params.next()? != "133" {
+            return None;
+        }
+
+        let kind = match params.next()? {
+            "A" => MARK_PROMPT_START,
+            "B" => MARK_COMMAND_START,
+            "C" => MARK_OUTPUT_START,
+            "D" => MARK_COMMAND_END,
+            _ => return None,
+        };
+        let exit_code = match kind {
+            MARK_COMMAND_END => params.next().and_then(|code| code.parse().ok()),
+            _ => None,
+        };
+        Some(Report::Mark(Mark { kind, exit_code, line: 0, column: 0 }))
+    }
+}
+
+/// Pass input to the parser, applying the reports in it
+pub(crate) fn advance<L: EventListener>(
+    parser: &mut Processor,
+    scanner: &mut ReportScanner,
+    tracker: &mut Tracker<'_, L>,
+    input: &[u8],
+) {
+    let mut start = 0;
+    for (end, report) in scanner.scan(input) {
+        parser.advance(tracker, &input[start..end]);
+        // Bytes held back for a synchronized update are applied first, so
+        // that the cursor is where the shell sent the mark
+        if parser.sync_bytes_count() > 0 {
+            parser.stop_sync(tracker);
+        }
+        match report {
+            Report::Mark(mark) => tracker.record_mark(mark),
+            Report::WorkingDirectory(url) => tracker.state.working_directory = Some(url),
+        }
+        start = end;
+    }
+    parser.advance(tracker, &input[start..]);
+}
+
+impl<L: EventListener> Tracker<'_, L> {
+    /// Whether the alternate screen is shown, leaving the marks of the
+    /// primary screen untouched
+    fn alternate(&self) -> bool {
+        self.term.mode().contains(TermMode::ALT_SCREEN)
+    }
+
+    /// Scrolling region as lines of the screen
+    pub(crate) fn region(&self) -> Range<usize> {
+        match self.state.scroll_region {
+            Some((top, bottom)) => top - 1..bottom.min(self.term.screen_lines()),
+            None => 0..self.term.screen_lines(),
+        }
+    }
+
+    /// Whether a line feed scrolls the region up
+    pub(crate) fn cursor_at_region_bottom(&self) -> bool {
+        self.term.grid().cursor.point.line.0 as usize + 1 == self.region().end
+    }
+
+    /// Move the marks with the lines when region scrolls up by n lines
+    pub(crate) fn marks_scroll_up(&mut self, region: Range<usize>, n: usize) {
+        if !self.alternate() {
+            self.state.marks.scroll_up(region, n);
+        }
+    }
+
+    /// Move the marks with the lines when region scrolls down by n lines
+    pub(crate) fn marks_scroll_down(&mut self, region: Range<usize>, n: usize) {
+        if !self.alternate() {
+            self.state.marks.scroll_down(region, n);
+        }
+    }
+
+    /// Move the marks before the screen is cleared, which scrolls the lines
+    /// up to the last one with content into the history
+    pub(crate) fn marks_clear_screen(&mut self) {
+        if self.alternate() {
+            return;
+        }
+
+        let grid = self.term.grid();
+        let lines = self.term.screen_lines();
+        let used = (0..lines)
+            .rev()
+            .find(|&y| grid[Line(y as i32)].into_iter().any(|cell| !cell.is_empty()))
+            .map_or(0, |y| y + 1);
+
+        let marks = &mut self.state.marks;
+        marks.scroll_up(0..lines, used);
+        let scrolled = marks.scrolled;
+        marks.list.retain(|m| m.line < scrolled);
+    }
+
+    /// Drop the marks of the history when it is cleared
+    pub(crate) fn marks_clear_history(&mut self) {
+        if !self.alternate() {
+            let scrolled = self.state.marks.scrolled;
+            self.state.marks.list.retain(|m| m.line >= scrolled);
+        }
+    }
+
+    /// Record a mark at the cursor
+    pub(crate) fn record_mark(&mut self, mut mark: Mark) {
+        if self.alternate() {
+            return;
+        }
+
+        let point = self.term.grid().cursor.point;
+        let history_size = self.term.grid().history_size();
+        let marks = &mut self.state.marks;
+        mark.line = marks.scrolled + point.line.0 as i64;
+        mark.column = point.column.0;
+        marks.prune(history_size);
+        marks.list.push(mark);
+    }
+}
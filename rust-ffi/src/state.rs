@@ -0,0 +1,949 @@
+//! Terminal state that alacritty keeps private, and serialization of the
+//! complete terminal state.
+
+use alacritty_terminal::event::EventListener;
+use alacritty_terminal::grid::{Dimensions, Grid, Row};
+use alacritty_terminal::index::{Column, Line, Point};
+use alacritty_terminal::selection::Selection;
+use alacritty_terminal::term::cell::Cell;
+use alacritty_terminal::term::color::COUNT;
+use alacritty_terminal::term::TermMode;
+use alacritty_terminal::vte::ansi::cursor_icon::CursorIcon;
+use alacritty_terminal::vte::ansi::{
+    Attr, CharsetIndex, ClearMode, CursorShape, CursorStyle, Handler, Hyperlink, KeyboardModes,
+    KeyboardModesApplyBehavior, LineClearMode, Mode, ModifyOtherKeys, NamedMode,
+    NamedPrivateMode, PrivateMode, Processor, Rgb, ScpCharPath, ScpUpdateMode, StandardCharset,
+    TabulationClearMode, Timeout,
+};
+use alacritty_terminal::Term;
+use serde::{Deserialize, Serialize};
+
+use crate::checkpoint::Checkpoints;
+use crate::clipboard::ClipboardRequests;
+use crate::shell::{self, ReportScanner, Marks};
+use crate::{CTerminal, Palette};
+
+/// Maximum depth of the title stack, matching alacritty
+const TITLE_STACK_MAX_DEPTH: usize = 4096;
+
+/// Most bytes of answers kept until the caller takes them
+const MAX_REPLIES: usize = 1 << 16;
+
+/// State that alacritty's Term does not expose, mirrored from the sequences
+/// that change it
+#[derive(Debug, Default, Clone)]
+pub(crate) struct TrackedState {
+    pub title: Option<String>,
+    pub title_stack: Vec<Option<String>>,
+    /// Scrolling region as the 1-based top and bottom lines of DECSTBM,
+    /// None for the whole screen
+    pub scroll_region: Option<(usize, usize)>,
+    pub active_charset: CharsetIndex,
+    /// Shell integration marks of the primary screen
+    pub marks: Marks,
+    /// URL of the working directory reported with OSC 7
+    pub working_directory: Option<String>,
+}
+
+/// Handler passing every sequence to the terminal while updating the
+/// tracked state
+pub(crate) struct Tracker<'a, L: EventListener> {
+    pub term: &'a mut Term<L>,
+    pub state: &'a mut TrackedState,
+    pub clipboard: &'a mut ClipboardRequests,
+    /// Colors the color table falls back to
+    pub palette: &'a Palette,
+    /// Answers to the application
+    pub replies: &'a mut Vec<u8>,
+    /// Checkpoints sharing the history, which copy it before it is dropped
+    pub checkpoints: Option<&'a mut Checkpoints>,
+}
+
+impl<L: EventListener> Handler for Tracker<'_, L> {
+    fn set_title(&mut self, title: Option<String>) {
+        self.state.title = title.clone();
+        self.term.set_title(title);
+    }
+
+    fn set_cursor_style(&mut self, style: Option<CursorStyle>) {
+        self.term.set_cursor_style(style);
+    }
+
+    fn set_cursor_shape(&mut self, shape: CursorShape) {
+        self.term.set_cursor_shape(shape);
+    }
+
+    fn input(&mut self, c: char) {
+        let at_bottom = self.cursor_at_region_bottom();
+        let cursor = &self.term.grid().cursor;
+        let (point, needs_wrap) = (cursor.point, cursor.input_needs_wrap);
+        self.term.input(c);
+
+        // Wrapping onto the next line moves the cursor back
+        let cursor = &self.term.grid().cursor;
+        let wrapped = cursor.point.column < point.column || (needs_wrap && !cursor.input_needs_wrap);
+        if at_bottom && wrapped && cursor.point.line == point.line {
+            self.marks_scroll_up(self.region(), 1);
+        }
+    }
+
+    fn goto(&mut self, line: i32, col: usize) {
+        self.term.goto(line, col);
+    }
+
+    fn goto_line(&mut self, line: i32) {
+        self.term.goto_line(line);
+    }
+
+    fn goto_col(&mut self, col: usize) {
+        self.term.goto_col(col);
+    }
+
+    fn insert_blank(&mut self, count: usize) {
+        self.term.insert_blank(count);
+    }
+
+    fn move_up(&mut self, lines: usize) {
+        self.term.move_up(lines);
+    }
+
+    fn move_down(&mut self, lines: usize) {
+        self.term.move_down(lines);
+    }
+
+    fn identify_terminal(&mut self, intermediate: Option<char>) {
+        self.term.identify_terminal(intermediate);
+    }
+
+    fn device_status(&mut self, arg: usize) {
+        self.term.device_status(arg);
+    }
+
+    fn move_forward(&mut self, cols: usize) {
+        self.term.move_forward(cols);
+    }
+
+    fn move_backward(&mut self, cols: usize) {
+        self.term.move_backward(cols);
+    }
+
+    fn move_down_and_cr(&mut self, lines: usize) {
+        self.term.move_down_and_cr(lines);
+    }
+
+    fn move_up_and_cr(&mut self, lines: usize) {
+        self.term.move_up_and_cr(lines);
+    }
+
+    fn put_tab(&mut self, count: u16) {
+        self.term.put_tab(count);
+    }
+
+    fn backspace(&mut self) {
+        self.term.backspace();
+    }
+
+    fn carriage_return(&mut self) {
+        self.term.carriage_return();
+    }
+
+    fn linefeed(&mut self) {
+        if self.cursor_at_region_bottom() {
+            self.marks_scroll_up(self.region(), 1);
+        }
+        self.term.linefeed();
+    }
+
+    fn bell(&mut self) {
+        self.term.bell();
+    }
+
+    fn substitute(&mut self) {
+        self.term.substitute();
+    }
+
+    fn newline(&mut self) {
+        if self.cursor_at_region_bottom() {
+            self.marks_scroll_up(self.region(), 1);
+        }
+        self.term.newline();
+    }
+
+    fn set_horizontal_tabstop(&mut self) {
+        self.term.set_horizontal_tabstop();
+    }
+
+    fn scroll_up(&mut self, lines: usize) {
+        self.marks_scroll_up(self.region(), lines);
+        self.term.scroll_up(lines);
+    }
+
+    fn scroll_down(&mut self, lines: usize) {
+        self.marks_scroll_down(self.region(), lines);
+        self.term.scroll_down(lines);
+    }
+
+    fn insert_blank_lines(&mut self, lines: usize) {
+        let region = self.region();
+        let line = self.term.grid().cursor.point.line.0 as usize;
+        if region.contains(&line) {
+            self.marks_scroll_down(line..region.end, lines);
+        }
+        self.term.insert_blank_lines(lines);
+    }
+
+    fn delete_lines(&mut self, lines: usize) {
+        let region = self.region();
+        let line = self.term.grid().cursor.point.line.0 as usize;
+        if region.contains(&line) {
+            self.marks_scroll_up(line..region.end, lines);
+        }
+        self.term.delete_lines(lines);
+    }
+
+    fn erase_chars(&mut self, count: usize) {
+        self.term.erase_chars(count);
+    }
+
+    fn delete_chars(&mut self, count: usize) {
+        self.term.delete_chars(count);
+    }
+
+    fn move_backward_tabs(&mut self, count: u16) {
+        self.term.move_backward_tabs(count);
+    }
+
+    fn move_forward_tabs(&mut self, count: u16) {
+        self.term.move_forward_tabs(count);
+    }
+
+    fn save_cursor_position(&mut self) {
+        self.term.save_cursor_position();
+    }
+
+    fn restore_cursor_position(&mut self) {
+        self.term.restore_cursor_position();
+    }
+
+    fn clear_line(&mut self, mode: LineClearMode) {
+        self.term.clear_line(mode);
+    }
+
+    fn clear_screen(&mut self, mode: ClearMode) {
+        match mode {
+            ClearMode::All => self.marks_clear_screen(),
+            ClearMode::Saved => {
+                self.detach_history();
+                self.marks_clear_history();
+            }
+            _ => (),
+        }
+        self.term.clear_screen(mode);
+    }
+
+    fn clear_tabs(&mut self, mode: TabulationClearMode) {
+        self.term.clear_tabs(mode);
+    }
+
+    fn reset_state(&mut self) {
+        // The shell stays in its directory
+        let working_directory = self.state.working_directory.take();
+        *self.state = TrackedState::default();
+        self.state.working_directory = working_directory;
+        self.detach_history();
+        self.term.reset_state();
+    }
+
+    fn reverse_index(&mut self) {
+        let region = self.region();
+        if self.term.grid().cursor.point.line.0 as usize == region.start {
+            self.marks_scroll_down(region, 1);
+        }
+        self.term.reverse_index();
+    }
+
+    fn terminal_attribute(&mut self, attr: Attr) {
+        self.term.terminal_attribute(attr);
+    }
+
+    fn set_mode(&mut self, mode: Mode) {
+        self.term.set_mode(mode);
+    }
+
+    fn unset_mode(&mut self, mode: Mode) {
+        self.term.unset_mode(mode);
+    }
+
+    fn report_mode(&mut self, mode: Mode) {
+        self.term.report_mode(mode);
+    }
+
+    fn set_private_mode(&mut self, mode: PrivateMode) {
+        self.term.set_private_mode(mode);
+    }
+
+    fn unset_private_mode(&mut self, mode: PrivateMode) {
+        self.term.unset_private_mode(mode);
+    }
+
+    fn report_private_mode(&mut self, mode: PrivateMode) {
+        self.term.report_private_mode(mode);
+    }
+
+    fn set_scrolling_region(&mut self, top: usize, bottom: Option<usize>) {
+        // Invalid regions are ignored by the terminal as well
+        let bottom = bottom.unwrap_or_else(|| self.term.screen_lines());
+        if top < bottom {
+            self.state.scroll_region = Some((top, bottom));
+        }
+        self.term.set_scrolling_region(top, Some(bottom));
+    }
+
+    fn set_keypad_application_mode(&mut self) {
+        self.term.set_keypad_application_mode();
+    }
+
+    fn unset_keypad_application_mode(&mut self) {
+        self.term.unset_keypad_application_mode();
+    }
+
+    fn set_active_charset(&mut self, index: CharsetIndex) {
+        self.state.active_charset = index;
+        self.term.set_active_charset(index);
+    }
+
+    fn configure_charset(&mut self, index: CharsetIndex, charset: StandardCharset) {
+        self.term.configure_charset(index, charset);
+    }
+
+    fn set_color(&mut self, index: usize, color: Rgb) {
+        self.term.set_color(index, color);
+    }
+
+    fn dynamic_color_sequence(&mut self, prefix: String, index: usize, terminator: &str) {
+        // Answer the query in xterm's format, which alacritty uses as well
+        let Some(&color) = crate::color_table(self.term.colors(), self.palette).get(index) else {
+            return;
+        };
+        let answer = format!(
+            "\x1b]{};rgb:{1:02x}{1:02x}/{2:02x}{2:02x}/{3:02x}{3:02x}{4}",
+            prefix, color.r, color.g, color.b, terminator
+        );
+        if self.replies.len() + answer.len() <= MAX_REPLIES {
+            self.replies.extend_from_slice(answer.as_bytes());
+        }
+    }
+
+    fn reset_color(&mut self, index: usize) {
+        self.term.reset_color(index);
+    }
+
+    fn clipboard_store(&mut self, clipboard: u8, base64: &[u8]) {
+        self.clipboard.store(clipboard, base64);
+    }
+
+    fn clipboard_load(&mut self, clipboard: u8, terminator: &str) {
+        self.clipboard.load(clipboard, terminator);
+    }
+
+    fn decaln(&mut self) {
+        self.term.decaln();
+    }
+
+    fn push_title(&mut self) {
+        if self.state.title_stack.len() >= TITLE_STACK_MAX_DEPTH {
+            self.state.title_stack.remove(0);
+        }
+        self.state.title_stack.push(self.state.title.clone());
+        self.term.push_title();
+    }
+
+    fn pop_title(&mut self) {
+        if let Some(title) = self.state.title_stack.pop() {
+            self.state.title = title;
+        }
+        self.term.pop_title();
+    }
+
+    fn text_area_size_pixels(&mut self) {
+        self.term.text_area_size_pixels();
+    }
+
+    fn text_area_size_chars(&mut self) {
+        self.term.text_area_size_chars();
+    }
+
+    fn set_hyperlink(&mut self, hyperlink: Option<Hyperlink>) {
+        self.term.set_hyperlink(hyperlink);
+    }
+
+    fn set_mouse_cursor_icon(&mut self, icon: CursorIcon) {
+        self.term.set_mouse_cursor_icon(icon);
+    }
+
+    fn report_keyboard_mode(&mut self) {
+        self.term.report_keyboard_mode();
+    }
+
+    fn push_keyboard_mode(&mut self, mode: KeyboardModes) {
+        self.term.push_keyboard_mode(mode);
+    }
+
+    fn pop_keyboard_modes(&mut self, to_pop: u16) {
+        self.term.pop_keyboard_modes(to_pop);
+    }
+
+    fn set_keyboard_mode(&mut self, mode: KeyboardModes, behavior: KeyboardModesApplyBehavior) {
+        self.term.set_keyboard_mode(mode, behavior);
+    }
+
+    fn set_modify_other_keys(&mut self, mode: ModifyOtherKeys) {
+        self.term.set_modify_other_keys(mode);
+    }
+
+    fn report_modify_other_keys(&mut self) {
+        self.term.report_modify_other_keys();
+    }
+
+    fn set_scp(&mut self, char_path: ScpCharPath, update_mode: ScpUpdateMode) {
+        self.term.set_scp(char_path, update_mode);
+    }
+}
+
+impl<L: EventListener> Tracker<'_, L> {
+    /// Let the checkpoints copy the history they share before the terminal
+    /// drops it
+    fn detach_history(&mut self) {
+        if let Some(checkpoints) = self.checkpoints.as_deref_mut() {
+            checkpoints.detach(&mut *self.term);
+        }
+    }
+}
+
+/// Escape sequence that begins a synchronized update
+const BEGIN_SYNC: &[u8] = b"\x1b[?2026h";
+
+/// Bytes of an escape sequence or UTF-8 character that the parser has
+/// started but not dispatched yet, and of a synchronized update that it
+/// holds back
+#[derive(Debug, Default, Clone)]
+pub(crate) struct SequenceTail {
+    buf: Vec<u8>,
+    /// Bytes held back for a synchronized update, None outside of one
+    sync: Option<Vec<u8>>,
+}
+
+impl SequenceTail {
+    /// Update the tail with bytes that were passed to parser
+    pub fn update(&mut self, input: &[u8], parser: &Processor) {
+        // The parser holds back the input after the sequence that began the
+        // update, so the bytes it holds are the last ones it was passed
+        if parser.sync_timeout().pending_timeout() {
+            let sync = self.sync.get_or_insert_with(Vec::new);
+            sync.extend_from_slice(input);
+            let start = sync.len().saturating_sub(parser.sync_bytes_count());
+            sync.drain(..start);
+        } else {
+            self.sync = None;
+        }
+
+        if let Some(i) = input.iter().rposition(|&b| b == 0x1b) {
+            self.buf.clear();
+            self.buf.extend_from_slice(&input[i..]);
+        } else if self.buf.first() == Some(&0x1b) && !sequence_complete(&self.buf) {
+            self.buf.extend_from_slice(input);
+        } else if input.len() >= 3 {
+            // A UTF-8 character started before this input ends inside it
+            self.buf.clear();
+            self.buf.extend_from_slice(&input[input.len() - 3..]);
+        } else {
+            self.buf.extend_from_slice(input);
+        }
+
+        if self.buf.first() != Some(&0x1b) || sequence_complete(&self.buf) {
+            let start = utf8_tail_start(&self.buf);
+            self.buf.drain(..start);
+        }
+    }
+
+    /// Bytes that bring a fresh parser into the same state. C0 controls
+    /// inside a sequence were already executed and are left out. The bytes
+    /// of a synchronized update were not parsed yet and are kept as they
+    /// are, after a sequence that begins the update again.
+    pub fn replay(&self) -> Vec<u8> {
+        if let Some(sync) = &self.sync {
+            return [BEGIN_SYNC, sync].concat();
+        }
+        self.buf.iter().copied().filter(|&b| b >= 0x20 || b == 0x1b).collect()
+    }
+}
+
+/// Whether the escape sequence at the start of buf has ended
+fn sequence_complete(buf: &[u8]) -> bool {
+    if buf.len() < 2 {
+        return false;
+    }
+    // CAN and SUB abort any sequence
+    if buf[1..].iter().any(|&b| b == 0x18 || b == 0x1a) {
+        return true;
+    }
+
+    match buf[1] {
+        b'[' => buf[2..].iter().any(|&b| (0x40..=0x7e).contains(&b)),
+        // Strings also end with ESC \, which starts a new tail
+        b']' | b'P' | b'X' | b'^' | b'_' => buf[2..].contains(&0x07),
+        _ => buf[1..].iter().any(|&b| !(0x20..=0x2f).contains(&b)),
+    }
+}
+
+/// Start of an incomplete UTF-8 character at the end of buf, or its length
+fn utf8_tail_start(buf: &[u8]) -> usize {
+    for i in (buf.len().saturating_sub(3)..buf.len()).rev() {
+        let width = match buf[i] {
+            0x00..=0x7f => return buf.len(),
+            0x80..=0xbf => continue,
+            0xc0..=0xdf => 2,
+            0xe0..=0xef => 3,
+            _ => 4,
+        };
+        return if buf.len() - i < width { i } else { buf.len() };
+    }
+    buf.len()
+}
+
+/// Identifies serialized terminal state and its format version, which
+/// changes with every change to TerminalState and the types it holds
+const STATE_MAGIC: &[u8; 4] = b"ATS\x03";
+
+/// Private modes restored by terminal_restore_state
+const PRIVATE_MODES: [(TermMode, NamedPrivateMode); 13] = [
+    (TermMode::APP_CURSOR, NamedPrivateMode::CursorKeys),
+    (TermMode::ORIGIN, NamedPrivateMode::Origin),
+    (TermMode::LINE_WRAP, NamedPrivateMode::LineWrap),
+    (TermMode::SHOW_CURSOR, NamedPrivateMode::ShowCursor),
+    (TermMode::MOUSE_REPORT_CLICK, NamedPrivateMode::ReportMouseClicks),
+    (TermMode::MOUSE_DRAG, NamedPrivateMode::ReportCellMouseMotion),
+    (TermMode::MOUSE_MOTION, NamedPrivateMode::ReportAllMouseMotion),
+    (TermMode::FOCUS_IN_OUT, NamedPrivateMode::ReportFocusInOut),
+    (TermMode::UTF8_MOUSE, NamedPrivateMode::Utf8Mouse),
+    (TermMode::SGR_MOUSE, NamedPrivateMode::SgrMouse),
+    (TermMode::ALTERNATE_SCROLL, NamedPrivateMode::AlternateScroll),
+    (TermMode::URGENCY_HINTS, NamedPrivateMode::UrgencyHints),
+    (TermMode::BRACKETED_PASTE, NamedPrivateMode::BracketedPaste),
+];
+
+/// Kitty keyboard protocol flags, as TermMode and KeyboardModes
+const KEYBOARD_MODES: [(TermMode, KeyboardModes); 5] = [
+    (TermMode::DISAMBIGUATE_ESC_CODES, KeyboardModes::DISAMBIGUATE_ESC_CODES),
+    (TermMode::REPORT_EVENT_TYPES, KeyboardModes::REPORT_EVENT_TYPES),
+    (TermMode::REPORT_ALTERNATE_KEYS, KeyboardModes::REPORT_ALTERNATE_KEYS),
+    (TermMode::REPORT_ALL_KEYS_AS_ESC, KeyboardModes::REPORT_ALL_KEYS_AS_ESC),
+    (TermMode::REPORT_ASSOCIATED_TEXT, KeyboardModes::REPORT_ASSOCIATED_TEXT),
+];
+
+#[derive(Clone, Serialize, Deserialize)]
+struct CursorState {
+    point: Point,
+    template: Cell,
+    charsets: [u8; 4],
+    input_needs_wrap: bool,
+}
+
+#[derive(Clone, Serialize, Deserialize)]
+struct GridState {
+    grid: Grid<Cell>,
+    cursor: CursorState,
+    saved_cursor: CursorState,
+}
+
+/// Complete state of a terminal
+#[derive(Clone, Serialize, Deserialize)]
+pub(crate) struct TerminalState {
+    columns: u32,
+    screen_lines: u32,
+    primary: GridState,
+    /// Alternate screen, present while it is active
+    alternate: Option<GridState>,
+    /// Modes as a MODE_* bitmask
+    modes: u32,
+    keyboard_modes: u8,
+    cursor_shape: u8,
+    cursor_blinking: bool,
+    tabs: Vec<usize>,
+    scroll_region: Option<(usize, usize)>,
+    active_charset: u8,
+    title: Option<String>,
+    title_stack: Vec<Option<String>>,
+    marks: Marks,
+    working_directory: Option<String>,
+    /// Palette entries changed from the defaults
+    colors: Vec<(usize, [u8; 3])>,
+    /// Unfinished escape sequence the parser is in
+    pending: Vec<u8>,
+}
+
+const CHARSET_INDICES: [CharsetIndex; 4] =
+    [CharsetIndex::G0, CharsetIndex::G1, CharsetIndex::G2, CharsetIndex::G3];
+
+pub(crate) const CURSOR_SHAPES: [CursorShape; 5] = [
+    CursorShape::Block,
+    CursorShape::Underline,
+    CursorShape::Beam,
+    CursorShape::HollowBlock,
+    CursorShape::Hidden,
+];
+
+fn charset_index(index: CharsetIndex) -> u8 {
+    CHARSET_INDICES.iter().position(|&i| i == index).unwrap_or(0) as u8
+}
+
+impl CursorState {
+    fn capture(cursor: &alacritty_terminal::grid::Cursor<Cell>) -> Self {
+        let mut charsets = [0; 4];
+        for (i, index) in CHARSET_INDICES.iter().enumerate() {
+            charsets[i] = match cursor.charsets[*index] {
+                StandardCharset::Ascii => 0,
+                StandardCharset::SpecialCharacterAndLineDrawing => 1,
+            };
+        }
+        CursorState {
+            point: cursor.point,
+            template: cursor.template.clone(),
+            charsets,
+            input_needs_wrap: cursor.input_needs_wrap,
+        }
+    }
+
+    fn apply(&self, cursor: &mut alacritty_terminal::grid::Cursor<Cell>) {
+        cursor.point = self.point;
+        cursor.template = self.template.clone();
+        for (i, index) in CHARSET_INDICES.iter().enumerate() {
+            cursor.charsets[*index] = match self.charsets[i] {
+                1 => StandardCharset::SpecialCharacterAndLineDrawing,
+                _ => StandardCharset::Ascii,
+            };
+        }
+        cursor.input_needs_wrap = self.input_needs_wrap;
+    }
+}
+
+impl GridState {
+    fn capture(grid: &Grid<Cell>, history: bool) -> Self {
+        let copy = if history {
+            grid.clone()
+        } else {
+            // Only the screen, without copying the history to drop it
+            let mut copy = Grid::new(grid.screen_lines(), grid.columns(), 0);
+            for line in 0..grid.screen_lines() as i32 {
+                copy[Line(line)] = grid[Line(line)].clone();
+            }
+            copy
+        };
+        GridState {
+            grid: copy,
+            cursor: CursorState::capture(&grid.cursor),
+            saved_cursor: CursorState::capture(&grid.saved_cursor),
+        }
+    }
+
+    fn to_grid(&self) -> Grid<Cell> {
+        let mut grid = self.grid.clone();
+        self.cursor.apply(&mut grid.cursor);
+        self.saved_cursor.apply(&mut grid.saved_cursor);
+        grid
+    }
+
+    /// Build the grid with rows as its history, oldest first
+    fn with_history(&self, rows: impl IntoIterator<Item = Row<Cell>>, max: usize) -> Grid<Cell> {
+        let lines = self.grid.screen_lines();
+        let mut grid = Grid::new(lines, self.grid.columns(), max);
+        for row in rows {
+            grid[Line(0)] = row;
+            grid.scroll_up(&(Line(0)..Line(lines as i32)), 1);
+        }
+        self.copy_screen(&mut grid);
+        grid
+    }
+
+    /// Copy the screen and the cursors into grid, which has the same size
+    fn copy_screen(&self, grid: &mut Grid<Cell>) {
+        for line in 0..self.grid.screen_lines() as i32 {
+            grid[Line(line)] = self.grid[Line(line)].clone();
+        }
+        self.cursor.apply(&mut grid.cursor);
+        self.saved_cursor.apply(&mut grid.saved_cursor);
+    }
+}
+
+impl TerminalState {
+    /// Columns and screen lines
+    pub fn size(&self) -> (usize, usize) {
+        (self.columns as usize, self.screen_lines as usize)
+    }
+
+    /// Primary grid with rows as its history, oldest first
+    pub fn primary_grid(&self, rows: impl IntoIterator<Item = Row<Cell>>, max: usize) -> Grid<Cell> {
+        self.primary.with_history(rows, max)
+    }
+
+    /// Copy the primary screen and its cursors into grid, which has the
+    /// same size
+    pub fn primary_screen(&self, grid: &mut Grid<Cell>) {
+        self.primary.copy_screen(grid);
+    }
+}
+
+/// Replace the grids of term, switching to the alternate screen if one is
+/// given
+fn install_grids<L: EventListener>(
+    term: &mut Term<L>,
+    primary: Grid<Cell>,
+    alternate: Option<Grid<Cell>>,
+) {
+    if term.mode().contains(TermMode::ALT_SCREEN) {
+        term.swap_alt();
+    }
+    *term.grid_mut() = primary;
+
+    if let Some(alternate) = alternate {
+        // Entering the alternate screen saves the primary cursor, which a
+        // primary grid captured behind the alternate screen already has
+        term.swap_alt();
+        *term.grid_mut() = alternate;
+    }
+}
+
+/// Alternate screen put aside while show_primary shows the primary screen
+pub(crate) struct Alternate {
+    grid: Grid<Cell>,
+    selection: Option<Selection>,
+}
+
+/// Show the primary grid, which is behind the alternate screen while that
+/// is shown. alacritty only gives access to the grid shown, so the screens
+/// are swapped; the alternate screen, which entering it again clears, and
+/// the selection are put aside for show_alternate. Returns None if the
+/// primary screen is shown already.
+pub(crate) fn show_primary<L: EventListener>(term: &mut Term<L>) -> Option<Alternate> {
+    if !term.mode().contains(TermMode::ALT_SCREEN) {
+        return None;
+    }
+
+    // The alternate screen has no history, so the copy is one screenful
+    let alternate = Alternate { grid: term.grid().clone(), selection: term.selection.take() };
+    term.swap_alt();
+    Some(alternate)
+}
+
+/// Show the alternate screen put aside by show_primary again, leaving the
+/// terminal as it was
+pub(crate) fn show_alternate<L: EventListener>(term: &mut Term<L>, alternate: Alternate) {
+    // Entering also copies the primary cursor into its saved cursor, which
+    // behind the alternate screen are the same already
+    term.swap_alt();
+    *term.grid_mut() = alternate.grid;
+    term.selection = alternate.selection;
+}
+
+/// Run f on the primary grid, which is behind the alternate screen while
+/// that is shown
+pub(crate) fn with_primary<L: EventListener, T>(
+    term: &mut Term<L>,
+    f: impl FnOnce(&mut Grid<Cell>) -> T,
+) -> T {
+    let alternate = show_primary(term);
+    let result = f(term.grid_mut());
+    if let Some(alternate) = alternate {
+        show_alternate(term, alternate);
+    }
+    result
+}
+
+/// Columns of the tab stops, found by moving the cursor through them
+fn tab_stops<L: EventListener>(term: &mut Term<L>) -> Vec<usize> {
+    let cursor = term.grid().cursor.clone();
+    let last = term.columns().saturating_sub(1);
+
+    let mut tabs = Vec::new();
+    term.grid_mut().cursor.point.column = Column(0);
+    loop {
+        term.move_forward_tabs(1);
+        let column = term.grid().cursor.point.column.0;
+        if column >= last || tabs.last() == Some(&column) {
+            break;
+        }
+        tabs.push(column);
+    }
+
+    term.grid_mut().cursor = cursor;
+    tabs
+}
+
+/// Serialize the complete state of a terminal
+pub(crate) fn save(terminal: &mut CTerminal, history: bool) -> Option<Vec<u8>> {
+    let state = capture(terminal, history);
+    let mut data = STATE_MAGIC.to_vec();
+    bincode::serialize_into(&mut data, &state).ok()?;
+    Some(data)
+}
+
+/// Replace the state of a terminal with state serialized by save
+pub(crate) fn restore(terminal: &mut CTerminal, data: &[u8]) -> Option<()> {
+    if !data.starts_with(STATE_MAGIC) {
+        return None;
+    }
+    let state: TerminalState = bincode::deserialize(&data[STATE_MAGIC.len()..]).ok()?;
+    if !fits(terminal, &state) {
+        return None;
+    }
+    let mut primary = state.primary.to_grid();
+    primary.update_history(terminal.config.scrolling_history);
+    apply(terminal, &state, primary);
+    Some(())
+}
+
+/// Copy the complete state of a terminal, which is left as it was. Bytes
+/// held back for a synchronized update are kept as pending input.
+pub(crate) fn capture(terminal: &mut CTerminal, history: bool) -> TerminalState {
+    let term = &mut terminal.term;
+    let alternate = term
+        .mode()
+        .contains(TermMode::ALT_SCREEN)
+        .then(|| GridState::capture(term.grid(), false));
+    let primary = with_primary(&mut *term, |grid| GridState::capture(grid, history));
+
+    let mode = *term.mode();
+    let modes = crate::MODE_FLAGS
+        .iter()
+        .filter(|(flag, _)| mode.contains(*flag))
+        .fold(0, |acc, (_, bit)| acc | bit);
+    let keyboard_modes = KEYBOARD_MODES
+        .iter()
+        .filter(|(flag, _)| mode.contains(*flag))
+        .fold(KeyboardModes::empty(), |acc, (_, keyboard)| acc | *keyboard);
+
+    let style = term.cursor_style();
+    let colors = (0..COUNT)
+        .filter_map(|i| term.colors()[i].map(|c| (i, [c.r, c.g, c.b])))
+        .collect();
+    let tabs = tab_stops(term);
+
+    TerminalState {
+        columns: terminal.size.columns,
+        screen_lines: terminal.size.screen_lines,
+        primary,
+        alternate,
+        modes,
+        keyboard_modes: keyboard_modes.bits(),
+        cursor_shape: CURSOR_SHAPES.iter().position(|&s| s == style.shape).unwrap_or(0) as u8,
+        cursor_blinking: style.blinking,
+        tabs,
+        scroll_region: terminal.state.scroll_region,
+        active_charset: charset_index(terminal.state.active_charset),
+        title: terminal.state.title.clone(),
+        title_stack: terminal.state.title_stack.clone(),
+        marks: terminal.state.marks.clone(),
+        working_directory: terminal.state.working_directory.clone(),
+        colors,
+        pending: terminal.tail.replay(),
+    }
+}
+
+/// Whether state is consistent and within the size limits of terminal
+pub(crate) fn fits(terminal: &CTerminal, state: &TerminalState) -> bool {
+    crate::check_size(&terminal.max_size, state.columns, state.screen_lines).is_none()
+        && state.primary.grid.columns() == state.columns as usize
+        && state.primary.grid.screen_lines() == state.screen_lines as usize
+}
+
+/// Replace the state of a terminal with a copy of state, which fits it,
+/// and primary as the primary grid
+pub(crate) fn apply(terminal: &mut CTerminal, state: &TerminalState, primary: Grid<Cell>) {
+    let size = crate::CTermSize { columns: state.columns, screen_lines: state.screen_lines };
+    let mut term = crate::new_term(&size, &terminal.config);
+    let mut tracked = TrackedState::default();
+    let mut parser = Processor::new();
+    let mut tracker = Tracker {
+        term: &mut term,
+        state: &mut tracked,
+        clipboard: &mut terminal.clipboard,
+        palette: &terminal.palette,
+        replies: &mut terminal.replies,
+        checkpoints: None,
+    };
+
+    if let Some((top, bottom)) = state.scroll_region {
+        tracker.set_scrolling_region(top, Some(bottom));
+    }
+
+    let modes = crate::MODE_FLAGS
+        .iter()
+        .filter(|(_, bit)| state.modes & bit != 0)
+        .fold(TermMode::empty(), |acc, (flag, _)| acc | *flag);
+    for (flag, private) in PRIVATE_MODES {
+        if !modes.contains(flag) {
+            tracker.unset_private_mode(PrivateMode::Named(private));
+        }
+    }
+    for (flag, private) in PRIVATE_MODES {
+        if modes.contains(flag) {
+            tracker.set_private_mode(PrivateMode::Named(private));
+        }
+    }
+    if modes.contains(TermMode::INSERT) {
+        tracker.set_mode(Mode::Named(NamedMode::Insert));
+    }
+    if modes.contains(TermMode::LINE_FEED_NEW_LINE) {
+        tracker.set_mode(Mode::Named(NamedMode::LineFeedNewLine));
+    }
+    if modes.contains(TermMode::APP_KEYPAD) {
+        tracker.set_keypad_application_mode();
+    }
+
+    tracker.clear_tabs(TabulationClearMode::All);
+    // The stop in the last column cannot be observed and keeps its default
+    let last = state.columns as usize - 1;
+    let default_last = (last % 8 == 0).then_some(last);
+    for column in state.tabs.iter().copied().chain(default_last) {
+        if column < state.columns as usize {
+            tracker.term.grid_mut().cursor.point.column = Column(column);
+            tracker.set_horizontal_tabstop();
+        }
+    }
+
+    tracker.set_active_charset(CHARSET_INDICES[state.active_charset as usize % 4]);
+    for &(index, [r, g, b]) in &state.colors {
+        tracker.set_color(index, Rgb { r, g, b });
+    }
+    for title in &state.title_stack {
+        tracker.set_title(title.clone());
+        tracker.push_title();
+    }
+    tracker.set_title(state.title.clone());
+    tracker.set_cursor_style(Some(CursorStyle {
+        shape: CURSOR_SHAPES[state.cursor_shape as usize % CURSOR_SHAPES.len()],
+        blinking: state.cursor_blinking,
+    }));
+
+    let alternate = state.alternate.as_ref().map(GridState::to_grid);
+    install_grids(&mut *tracker.term, primary, alternate);
+
+    // Switching screens replaces the keyboard mode
+    let keyboard_modes = KeyboardModes::from_bits_truncate(state.keyboard_modes);
+    if !keyboard_modes.is_empty() {
+        tracker.push_keyboard_mode(keyboard_modes);
+    }
+
+    tracker.state.marks = state.marks.clone();
+    tracker.state.working_directory = state.working_directory.clone();
+    let mut scanner = ReportScanner::default();
+    shell::advance(&mut parser, &mut scanner, &mut tracker, &state.pending);
+
+    terminal.term = term;
+    terminal.parser = parser;
+    terminal.size = size;
+    terminal.state = tracked;
+    terminal.scanner = scanner;
+    terminal.tail = SequenceTail::default();
+    terminal.tail.update(&state.pending, &terminal.parser);
+}